@@ -2,19 +2,24 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/signal"
-	"strings"
+	"path/filepath"
 	"syscall"
 
 	"github.com/kazuki-sk/codepack/internal/config"
-	"github.com/kazuki-sk/codepack/internal/ignorer"
-	"github.com/kazuki-sk/codepack/internal/language"
+	"github.com/kazuki-sk/codepack/internal/git"
+	"github.com/kazuki-sk/codepack/internal/godeps"
 	"github.com/kazuki-sk/codepack/internal/output"
-	"github.com/kazuki-sk/codepack/internal/processor"
+	"github.com/kazuki-sk/codepack/internal/source"
 	"github.com/kazuki-sk/codepack/internal/ui"
+	"github.com/kazuki-sk/codepack/pkg/codepack"
 )
 
 // ldflags で設定されるバージョン情報
@@ -42,13 +47,30 @@ func run(args []string) int {
 		return 0
 	}
 
+	// 1.1 進捗表示とロガーの構築 (--verbose / --quiet / --log-format)
+	// 進捗表示は標準エラー出力が端末の場合のみ有効とし、ログは進捗行を消去してから出力する
+	var progress *ui.Progress
+	logOut := io.Writer(os.Stderr)
+	if !cfg.Quiet && !cfg.NoProgress && ui.IsTerminal(os.Stderr) {
+		progress = ui.NewProgress(os.Stderr)
+		logOut = progress.LogWriter()
+	}
+	logger := newLogger(cfg, logOut)
+
 	// 2. ルートコンテキストとシグナルハンドリングのセットアップ
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
 	// 3. UIコンポーネントの初期化
 	// InputPortのライフサイクル管理はここ(Composition Root)で行う
-	inputPort := ui.NewStandardInput()
+	// 標準入力をソースとして読む場合は対話に使えないため、読み取りゴルーチンを起動しない
+	var inputPort ui.InputPort
+	readsStdin := cfg.TargetDir == source.StdinSpec
+	if readsStdin {
+		inputPort = ui.NewNullInput()
+	} else {
+		inputPort = ui.NewStandardInput()
+	}
 	
 	// キャンセル監視用ゴルーチン
 	go func() {
@@ -56,46 +78,201 @@ func run(args []string) int {
 		inputPort.Close()
 	}()
 
+	// 対話できない入力の場合（標準入力が端末でない・ソースとして使用中）、
+	// --large-policy 未指定なら Console がプロンプトを出さずに決定的なデフォルトを使用する
 	largeFileOpts := ui.LargeFileOptions{
-		ForceLarge: cfg.ForceLarge,
-		SkipLarge:  cfg.SkipLarge,
+		Policy: ui.LargeFilePolicy(cfg.LargePolicy),
 	}
 	console := ui.NewConsole(inputPort, os.Stderr, largeFileOpts)
+	if progress != nil {
+		console.AttachProgress(progress)
+	}
 	
-	// 4. Ignorer (除外ロジック) の構築
-	ignr := ignorer.NewIgnorer()
-	
-	// これを最初に行うことで、後続のCLI設定などが優先（または追加）される順序になります。
-	if err := ignr.LoadDefaults(); err != nil {
-		// 埋め込みリソースの読み込み失敗は致命的な内部エラー
-		fmt.Fprintf(os.Stderr, "Error loading default ignore rules: %v\n", err)
-		return 1
+	// 4. 走査対象ファイルシステムの構築
+	// --rev 指定時は git のオブジェクトDBから、それ以外はディレクトリ・アーカイブ・標準入力から読み出す
+	var (
+		fsys    fs.FS
+		diskDir string // 自己参照判定用。ディスク上のディレクトリ以外では空
+	)
+	if cfg.Revision != "" {
+		revFS, err := git.OpenRevision(ctx, cfg.TargetDir, cfg.Revision)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading revision: %v\n", err)
+			return 1
+		}
+		defer revFS.Close()
+		fsys = revFS
+	} else {
+		src, err := source.Open(cfg.TargetDir, os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening source: %v\n", err)
+			return 1
+		}
+		defer src.Close()
+		fsys = src.FS
+		diskDir = src.Dir
 	}
 
-	// 4.1 CLI パターン (-p) の追加
-	if len(cfg.IgnorePatterns) > 0 {
-		patternsText := strings.Join(cfg.IgnorePatterns, "\n")
-		ignr.AddMatcher(ignorer.NewGitIgnoreMatcher(strings.NewReader(patternsText)))
+	// 5. Packer オプションの構築
+	// 組み込みの除外ルールと CLI パターン (-p) は Packer 側で適用される
+	opts := codepack.Options{
+		IgnorePatterns:     cfg.IgnorePatterns,
+		LargeFileHandler:   console, // LargeFileHandlerとして注入
+		TextPatterns:       cfg.TextPatterns,
+		BinaryPatterns:     cfg.BinaryPatterns,
+		HexDumpBytes:       cfg.HexDumpBytes,
+		StripComments:      cfg.StripComments,
+		KeepDocComments:    cfg.KeepDocComments,
+		CollapseBlankLines: cfg.CollapseBlank,
+		LineNumbers:        cfg.LineNumbers,
+		SkipGenerated:      cfg.SkipGenerated || cfg.GeneratedStub,
+		RedactSecrets:      cfg.RedactSecrets,
+		FailOnSecret:       cfg.FailOnSecret,
+		Outline:            cfg.Outline,
+		RawNotebooks:       cfg.RawNotebooks,
+		MaxFileSize:        cfg.MaxFileSize,
+		Truncation:         truncation(cfg),
+		CombinedDiff:       cfg.DiffStyle == "combined",
+		Dir:                diskDir,
+		OutputFile:         cfg.OutputFile,
+		Logger:             logger,
+		Strict:             cfg.Strict,
+	}
+	// ライブラリでは 0 がデフォルト値を表すため、出力を含めない指定は負の値に変換する
+	opts.NotebookOutputLines = cfg.NotebookOutputs
+	if cfg.NotebookOutputs == 0 {
+		opts.NotebookOutputLines = -1
+	}
+	opts.GeneratedPlaceholder = cfg.GeneratedStub
+	opts.DisableGitAttributes = cfg.NoGitAttributes
+	for _, o := range cfg.SizeOverrides {
+		opts.SizeOverrides = append(opts.SizeOverrides, codepack.SizeRule{Pattern: o.Pattern, Size: o.Size})
+	}
+	if cfg.SecretAllowlist != "" {
+		allow, err := codepack.LoadSecretAllowlist(cfg.SecretAllowlist)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading secrets allowlist: %v\n", err)
+			return 1
+		}
+		opts.SecretAllowlist = allow
+	}
+	if cfg.ReplaceRules != "" {
+		rules, err := codepack.LoadReplaceRules(cfg.ReplaceRules)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading replace rules from %s: %v\n", cfg.ReplaceRules, err)
+			return 1
+		}
+		opts.Replacements = rules
+	}
+	if progress != nil {
+		opts.OnVisit = progress.Visit
+		opts.OnEntry = progress.Packed
 	}
 
-	// 4.2 CLI 指定ファイル (-i) の追加
+	// 5.1 除外ファイルの読み込み
+	// 優先順: CLI 指定ファイル (-i) → ローカル設定 (.code-packignore) → 標準設定 (.gitignore, .dockerignore)
+	cwd := os.DirFS(".")
 	for _, path := range cfg.IgnoreFiles {
-		if err := ignr.LoadIgnoreFile(path); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to load ignore file '%s': %v\n", path, err)
+		m, err := codepack.LoadIgnoreFile(os.DirFS(filepath.Dir(path)), filepath.Base(path))
+		if err != nil {
+			logger.Warn("failed to load ignore file", "path", path, "error", err)
+			continue
+		}
+		if m != nil {
+			// レポート上の出所はファイル名ではなく指定されたパスとする
+			m = codepack.NamedMatcher(path, m)
 		}
+		opts.Matchers = appendMatcher(opts.Matchers, m)
 	}
+	m, _ := codepack.LoadIgnoreFile(cwd, ".code-packignore")
+	opts.Matchers = appendMatcher(opts.Matchers, m)
 
-	// 4.3 ローカル設定 (.code-packignore)
-	ignr.LoadIgnoreFile(".code-packignore")
+	// 標準設定は、ディスク上のディレクトリ以外（リビジョン・アーカイブ）ではソース内の内容で評価する
+	stdIgnoreFS := cwd
+	if diskDir == "" {
+		stdIgnoreFS = fsys
+	}
+	for _, name := range []string{".gitignore", ".dockerignore"} {
+		m, err := codepack.LoadIgnoreFile(stdIgnoreFS, name)
+		if err != nil {
+			logger.Warn("failed to load ignore file", "path", name, "error", err)
+			continue
+		}
+		opts.Matchers = appendMatcher(opts.Matchers, m)
+	}
 
-	// 4.4 標準設定 (.gitignore, .dockerignore)
-	ignr.LoadIgnoreFile(".gitignore")
-	ignr.LoadIgnoreFile(".dockerignore")
+	// 5.2 Go の依存関係による絞り込み (--go-deps)
+	// 起点のパッケージから import を辿り、含まれないファイルを除外する。詰め込まない依存は先頭に一覧を出力する
+	if cfg.GoDeps != "" {
+		graph, err := godeps.Resolve(fsys, cfg.GoDeps)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving Go dependencies: %v\n", err)
+			return 1
+		}
+		opts.Matchers = append(opts.Matchers, codepack.NamedMatcher("go-deps", graph))
+		opts.Preamble = graph.Markdown()
+	}
 
-	// 5. Language Mapper の初期化
-	mapper, err := language.NewMapper(cfg.LanguageMap)
+	// 5.3 Go のシンボルによる絞り込み (--symbol)
+	// ファイル全体ではなく、定義・使用している型・呼び出し元の宣言のみを出力する
+	if len(cfg.Symbols) > 0 {
+		syms, err := godeps.FindSymbols(fsys, cfg.Symbols)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving symbols: %v\n", err)
+			return 1
+		}
+		for _, r := range syms.Refs {
+			opts.Snippets = append(opts.Snippets, codepack.Snippet{Path: r.Path, Start: r.Start, End: r.End, Note: r.Note()})
+		}
+		opts.SnippetsOnly = true
+		opts.Preamble = syms.Markdown()
+	}
+
+	// 5.4 行の範囲・宣言の指定 (--snippet, --snippets-from)
+	// 走査したファイル全体に加えて、指定した部分のみを出力する
+	specs := []codepack.Snippet{}
+	for _, spec := range cfg.Snippets {
+		s, err := codepack.ParseSnippet(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error parsing snippet: %v\n", err)
+			return 1
+		}
+		specs = append(specs, s)
+	}
+	if cfg.SnippetFile != "" {
+		s, err := codepack.LoadSnippetFile(cfg.SnippetFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading snippet specs from %s: %v\n", cfg.SnippetFile, err)
+			return 1
+		}
+		specs = append(specs, s...)
+	}
+	opts.Snippets = append(opts.Snippets, specs...)
+
+	// 5.5 言語マップ (-m)
+	if cfg.LanguageMap != "" {
+		langMap, err := codepack.LoadLanguageMap(cfg.LanguageMap)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error initializing language mapper: %v\n", err)
+			return 1
+		}
+		opts.LanguageMap = langMap
+	}
+
+	// 5.6 差分の取得 (--with-diff)
+	// インターフェース型のフィールドに nil ポインタを代入しないよう、取得時のみ設定する
+	if cfg.DiffRef != "" {
+		d, err := git.LoadDiff(ctx, cfg.TargetDir, cfg.DiffRef, cfg.Revision)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading diff: %v\n", err)
+			return 1
+		}
+		opts.Diff = d
+	}
+
+	packer, err := codepack.New(opts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing language mapper: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error initializing packer: %v\n", err)
 		return 1
 	}
 
@@ -112,7 +289,7 @@ func run(args []string) int {
 	}
 
 	if cfg.CopyToClipboard {
-		clipStrategy := output.NewClipboardStrategy(ctx)
+		clipStrategy := output.NewClipboardStrategy(ctx, logger)
 		strategies = append(strategies, clipStrategy)
 	}
 
@@ -128,24 +305,38 @@ func run(args []string) int {
 		}
 	}()
 
-	// 7. Processor (Core Logic) の初期化と依存注入
-	proc, err := processor.NewProcessor(
-		cfg.TargetDir,
-		cfg.OutputFile,
-		ignr,
-		mapper,
-		outStrategy,
-		console, // LargeFileHandlerとして注入
-	)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing processor: %v\n", err)
-		return 1
+	// 7. 実行
+	if !cfg.Quiet {
+		if cfg.Revision != "" {
+			fmt.Fprintf(os.Stderr, "Packing code from %s at %s...\n", cfg.TargetDir, cfg.Revision)
+		} else {
+			fmt.Fprintf(os.Stderr, "Packing code from %s...\n", cfg.TargetDir)
+		}
 	}
-
-	// 8. 実行
-	fmt.Fprintf(os.Stderr, "Packing code from %s...\n", cfg.TargetDir)
 	
-	if err := proc.Execute(ctx); err != nil {
+	var out io.Writer = outStrategy
+	if progress != nil {
+		out = progress.CountingWriter(outStrategy)
+		progress.Start()
+	}
+	stats, err := packer.Pack(ctx, fsys, out)
+	if progress != nil {
+		progress.Stop()
+	}
+
+	// 中断時以外は、失敗した場合でもそこまでの結果をレポートする（CIでの原因調査用）
+	if cfg.ReportFile != "" && !errors.Is(err, context.Canceled) {
+		if rerr := writeReport(cfg.ReportFile, stats, err); rerr != nil {
+			logger.Warn("failed to write report", "path", cfg.ReportFile, "error", rerr)
+		}
+	}
+
+	// Strict モード・秘密情報の検出による失敗時も、どこまで処理できたかを確認できるよう集計は表示する
+	if !cfg.Quiet && (err == nil || errors.Is(err, codepack.ErrUnreadable) || errors.Is(err, codepack.ErrSecretsFound)) {
+		ui.PrintSummary(os.Stderr, stats)
+	}
+
+	if err != nil {
 		// レビュー指摘対応: UI層の内部エラー(ui.ErrInputClosed)への依存を排除し、
 		// context.Canceled の判定に統一。
 		if errors.Is(err, context.Canceled) {
@@ -157,6 +348,71 @@ func run(args []string) int {
 		return 1
 	}
 
-	fmt.Fprintln(os.Stderr, "Done.")
+	if !cfg.Quiet {
+		fmt.Fprintln(os.Stderr, "Done.")
+	}
 	return 0
 }
+
+// newLogger は設定に応じたレベル・形式のロガーを標準エラー出力に構築します。
+// 通常は Info 以上（大容量ファイルの除外や読み込みエラー）、--verbose では全てのスキップを記録します。
+func newLogger(cfg *config.Config, w io.Writer) *slog.Logger {
+	level := slog.LevelInfo
+	switch {
+	case cfg.Verbose:
+		level = slog.LevelDebug
+	case cfg.Quiet:
+		level = slog.LevelError
+	}
+
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	if cfg.LogFormat == "json" {
+		return slog.New(slog.NewJSONHandler(w, handlerOpts))
+	}
+	return slog.New(slog.NewTextHandler(w, handlerOpts))
+}
+
+// truncation は --truncate-* の指定から切り詰めの設定を構築します。
+func truncation(cfg *config.Config) codepack.Truncation {
+	t := codepack.Truncation{
+		Lines:     cfg.TruncateLines,
+		TailLines: cfg.TruncateTail,
+		Bytes:     cfg.TruncateBytes,
+	}
+	switch cfg.TruncateMode {
+	case "head-tail":
+		t.Mode = codepack.TruncateHeadTail
+	case "bytes":
+		t.Mode = codepack.TruncateBytes
+	}
+	return t
+}
+
+// appendMatcher は存在しなかったignoreファイル（nil）を除いて追加します。
+func appendMatcher(ms []codepack.Matcher, m codepack.Matcher) []codepack.Matcher {
+	if m == nil {
+		return ms
+	}
+	return append(ms, m)
+}
+
+// writeReport は実行結果を JSON 形式でファイルへ書き出します。
+func writeReport(path string, stats codepack.Stats, runErr error) error {
+	report := struct {
+		Version string `json:"version"`
+		Error   string `json:"error,omitempty"`
+		codepack.Stats
+	}{
+		Version: version,
+		Stats:   stats,
+	}
+	if runErr != nil {
+		report.Error = runErr.Error()
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
@@ -2,23 +2,68 @@ package config
 
 // Config はアプリケーションの実行設定を保持します。
 type Config struct {
-	TargetDir       string
+	TargetDir       string // ディレクトリ、アーカイブ(zip/tar/tar.gz)、または "-"（標準入力）
 	OutputFile      string
 	CopyToClipboard bool
-	IgnorePatterns  []string // -p flags
-	IgnoreFiles     []string // -i flags
-	LanguageMap     string   // -m flag
-	ForceLarge      bool     // --force-large
-	SkipLarge       bool     // --skip-large
+	IgnorePatterns  []string       // -p flags
+	IgnoreFiles     []string       // -i flags
+	LanguageMap     string         // -m flag
+	TextPatterns    []string       // --text
+	BinaryPatterns  []string       // --binary
+	HexDumpBytes    int            // --hex-dump
+	RawNotebooks    bool           // --raw-notebooks
+	Outline         bool           // --outline
+	StripComments   bool           // --strip-comments
+	KeepDocComments bool           // --keep-doc-comments
+	CollapseBlank   bool           // --collapse-blank-lines
+	LineNumbers     bool           // --line-numbers
+	NoGitAttributes bool           // --no-gitattributes
+	SkipGenerated   bool           // --skip-generated
+	GeneratedStub   bool           // --generated-placeholder
+	RedactSecrets   bool           // --redact-secrets
+	SecretAllowlist string         // --secrets-allowlist
+	FailOnSecret    bool           // --fail-on-secret
+	ReplaceRules    string         // --replace-rules
+	NotebookOutputs int            // --notebook-outputs（0 の場合は出力を含めない）
+	LargePolicy     string         // --large-policy (ask | include | skip | truncate)。空の場合は ask
+	MaxFileSize     int64          // --max-file-size（パターンなし）。0 の場合はデフォルト
+	SizeOverrides   []SizeOverride // --max-file-size PATTERN=SIZE
+	TruncateMode    string         // --truncate-mode (head | head-tail | bytes)
+	TruncateLines   int            // --truncate-lines
+	TruncateTail    int            // --truncate-tail-lines
+	TruncateBytes   int64          // --truncate-bytes
+	GoDeps          string         // --go-deps（起点の Go パッケージのディレクトリ）
+	Symbols         []string       // --symbol（pkg.Func など）
+	Snippets        []string       // --snippet（path:120-180 または path#run）
+	SnippetFile     string         // --snippets-from
+	Revision        string         // --rev
+	DiffRef         string         // --with-diff
+	DiffStyle       string         // --diff-style (inline | combined)
+	ReportFile      string         // --report
+	Verbose         bool           // --verbose
+	Quiet           bool           // --quiet
+	LogFormat       string         // --log-format (text | json)
+	Strict          bool           // --strict
+	NoProgress      bool           // --no-progress
 	ShowVersion     bool
 }
 
+// SizeOverride はパターンごとの大容量ファイル閾値です (--max-file-size "*.sql=2MB")。
+type SizeOverride struct {
+	Pattern string
+	Size    int64
+}
+
 // DefaultConfig はデフォルト設定を返します。
 func DefaultConfig() *Config {
 	return &Config{
-		TargetDir:      ".",
-		OutputFile:     "codebase.md",
-		IgnorePatterns: []string{},
-		IgnoreFiles:    []string{},
+		TargetDir:       ".",
+		OutputFile:      "codebase.md",
+		IgnorePatterns:  []string{},
+		IgnoreFiles:     []string{},
+		TruncateMode:    "head",
+		NotebookOutputs: 20,
+		DiffStyle:       "inline",
+		LogFormat:       "text",
 	}
 }
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"path"
 )
 
 // arrayFlags はフラグで複数回指定可能な文字列スライスを扱います。
@@ -29,7 +30,7 @@ func Load(args []string, out io.Writer) (*Config, error) {
 	// Note: cmd側で詳細なUsageを表示するため、ここではデフォルトの挙動のままにするか、
 	// シンプルなエラーメッセージのみを出力するように設計します。
 
-	fs.StringVar(&cfg.TargetDir, "d", cfg.TargetDir, "Target directory")
+	fs.StringVar(&cfg.TargetDir, "d", cfg.TargetDir, "Target directory or archive (zip, tar, tar.gz, - for stdin)")
 	fs.StringVar(&cfg.OutputFile, "o", cfg.OutputFile, "Output file")
 	fs.BoolVar(&cfg.CopyToClipboard, "c", false, "Copy to clipboard")
 	
@@ -38,21 +39,156 @@ func Load(args []string, out io.Writer) (*Config, error) {
 	fs.Var(&ignores, "i", "Ignore files")
 
 	fs.StringVar(&cfg.LanguageMap, "m", "", "Language map JSON")
-	fs.BoolVar(&cfg.ForceLarge, "force-large", false, "Force include large files")
-	fs.BoolVar(&cfg.SkipLarge, "skip-large", false, "Skip large files")
+
+	var textGlobs, binaryGlobs arrayFlags
+	fs.Var(&textGlobs, "text", "Always treat files matching the glob as text (repeatable)")
+	fs.Var(&binaryGlobs, "binary", "Always treat files matching the glob as binary (repeatable)")
+	fs.IntVar(&cfg.HexDumpBytes, "hex-dump", 0, "Include a hex dump of the first N bytes of binary files")
+	fs.BoolVar(&cfg.StripComments, "strip-comments", false, "Remove comments (C-like, hash, SQL, HTML/XML and Lisp syntax)")
+	fs.BoolVar(&cfg.KeepDocComments, "keep-doc-comments", false, "Keep doc comments with --strip-comments")
+	fs.BoolVar(&cfg.CollapseBlank, "collapse-blank-lines", false, "Collapse runs of blank lines into one")
+	fs.BoolVar(&cfg.LineNumbers, "line-numbers", false, "Prefix each line of file contents with its line number")
+	fs.BoolVar(&cfg.NoGitAttributes, "no-gitattributes", false, "Ignore .gitattributes (export-ignore, linguist-language, binary and -diff)")
+	fs.BoolVar(&cfg.SkipGenerated, "skip-generated", false, "Skip generated and vendored files (DO NOT EDIT headers, generator markers, linguist-generated/linguist-vendored)")
+	fs.BoolVar(&cfg.GeneratedStub, "generated-placeholder", false, "With --skip-generated, keep skipped files as a one-line entry")
+	fs.BoolVar(&cfg.RedactSecrets, "redact-secrets", false, "Replace API keys, tokens, private keys and passwords with [REDACTED:rule-id]")
+	fs.StringVar(&cfg.SecretAllowlist, "secrets-allowlist", "", "File of regular expressions (one per line) for values that are not redacted")
+	fs.BoolVar(&cfg.FailOnSecret, "fail-on-secret", false, "Redact secrets and exit with an error if any were found")
+	fs.StringVar(&cfg.ReplaceRules, "replace-rules", "", "JSON file of {pattern, replacement, glob, paths} rules applied to file contents")
+	fs.BoolVar(&cfg.Outline, "outline", false, "Reduce source files to declarations and signatures (Go exactly; TS/JS, Python, Java, Rust, C/C++/C# heuristically)")
+	fs.BoolVar(&cfg.RawNotebooks, "raw-notebooks", false, "Pack Jupyter notebooks as raw JSON instead of cells")
+	fs.IntVar(&cfg.NotebookOutputs, "notebook-outputs", cfg.NotebookOutputs, "Output lines kept per notebook cell (0 drops outputs)")
+	fs.StringVar(&cfg.LargePolicy, "large-policy", "", "Large file handling: ask, include, skip or truncate (default ask; skip when stdin is not a terminal)")
+	var maxSizes arrayFlags
+	var truncateBytes string
+	fs.Var(&maxSizes, "max-file-size", "Large file threshold such as 2MB, or PATTERN=SIZE for matching files (repeatable, default 500KB)")
+	fs.StringVar(&cfg.TruncateMode, "truncate-mode", cfg.TruncateMode, "How large files are truncated: head, head-tail or bytes")
+	fs.IntVar(&cfg.TruncateLines, "truncate-lines", 0, "Lines kept when a large file is truncated (default 200)")
+	fs.IntVar(&cfg.TruncateTail, "truncate-tail-lines", 0, "Trailing lines kept with --truncate-mode=head-tail (default 50)")
+	fs.StringVar(&truncateBytes, "truncate-bytes", "", "Bytes kept with --truncate-mode=bytes, such as 64KB (default 64KB)")
+
+	// 後方互換: --force-large / --skip-large は --large-policy の別名として扱う
+	var forceLarge, skipLarge bool
+	fs.BoolVar(&forceLarge, "force-large", false, "Alias for --large-policy=include")
+	fs.BoolVar(&skipLarge, "skip-large", false, "Alias for --large-policy=skip")
+	fs.StringVar(&cfg.GoDeps, "go-deps", "", "Pack only the Go package in the given directory (such as ./cmd/server) and the local packages it imports")
+	var symbols arrayFlags
+	fs.Var(&symbols, "symbol", "Pack only the definition, used types and callers of a Go symbol such as pkg.Func or pkg.Type.Method (repeatable)")
+	var snippets arrayFlags
+	fs.Var(&snippets, "snippet", "Also pack a line range or declaration such as path/to/file.go:120-180 or main.go#run (repeatable)")
+	fs.StringVar(&cfg.SnippetFile, "snippets-from", "", "Read snippet specs from a file, one per line")
+	fs.StringVar(&cfg.Revision, "rev", "", "Pack the tree at the given git revision")
+	fs.StringVar(&cfg.DiffRef, "with-diff", "", "Append git diff against the given ref")
+	fs.StringVar(&cfg.DiffStyle, "diff-style", cfg.DiffStyle, "Diff layout: inline or combined")
+	fs.StringVar(&cfg.ReportFile, "report", "", "Write a JSON run report to the given file")
+	fs.BoolVar(&cfg.Verbose, "verbose", false, "Log every skipped file with its reason")
+	fs.BoolVar(&cfg.Quiet, "quiet", false, "Only log errors and suppress the summary")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "Log format: text or json")
+	fs.BoolVar(&cfg.Strict, "strict", false, "Exit with an error if any file cannot be read")
+	fs.BoolVar(&cfg.NoProgress, "no-progress", false, "Disable the progress line on a terminal")
 	fs.BoolVar(&cfg.ShowVersion, "v", false, "Show version")
 	fs.BoolVar(&cfg.ShowVersion, "version", false, "Show version")
 
-	if err := fs.Parse(args); err != nil {
-		return nil, err
+	// 位置引数とフラグの混在を許可するため、位置引数を取り除きながら繰り返し解析する
+	var positional []string
+	for rest := args; ; {
+		if err := fs.Parse(rest); err != nil {
+			return nil, err
+		}
+		rest = fs.Args()
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		rest = rest[1:]
 	}
 
 	cfg.IgnorePatterns = patterns
 	cfg.IgnoreFiles = ignores
+	cfg.TextPatterns = textGlobs
+	cfg.Symbols = symbols
+	cfg.Snippets = snippets
+	cfg.BinaryPatterns = binaryGlobs
+	for _, g := range append(append([]string{}, textGlobs...), binaryGlobs...) {
+		if _, err := path.Match(g, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", g, err)
+		}
+	}
 
-	if cfg.ForceLarge && cfg.SkipLarge {
+	// 位置引数はソースの指定として扱う（-d より優先）
+	switch len(positional) {
+	case 0:
+	case 1:
+		cfg.TargetDir = positional[0]
+	default:
+		return nil, fmt.Errorf("too many arguments: %v", positional)
+	}
+
+	if forceLarge && skipLarge {
 		return nil, errors.New("--force-large and --skip-large cannot be used together")
 	}
+	if (forceLarge || skipLarge) && cfg.LargePolicy != "" {
+		return nil, errors.New("--force-large/--skip-large cannot be combined with --large-policy")
+	}
+	switch {
+	case forceLarge:
+		cfg.LargePolicy = "include"
+	case skipLarge:
+		cfg.LargePolicy = "skip"
+	}
+
+	switch cfg.LargePolicy {
+	case "", "ask", "include", "skip", "truncate":
+	default:
+		return nil, fmt.Errorf("invalid --large-policy %q (want ask, include, skip or truncate)", cfg.LargePolicy)
+	}
+	if cfg.TruncateLines < 0 {
+		return nil, errors.New("--truncate-lines must not be negative")
+	}
+	if cfg.KeepDocComments && !cfg.StripComments {
+		return nil, errors.New("--keep-doc-comments requires --strip-comments")
+	}
+	if len(cfg.Symbols) > 0 && cfg.GoDeps != "" {
+		return nil, errors.New("--symbol cannot be combined with --go-deps")
+	}
+	if cfg.NotebookOutputs < 0 {
+		return nil, errors.New("--notebook-outputs must not be negative")
+	}
+	if cfg.HexDumpBytes < 0 {
+		return nil, errors.New("--hex-dump must not be negative")
+	}
+	if cfg.TruncateTail < 0 {
+		return nil, errors.New("--truncate-tail-lines must not be negative")
+	}
+	switch cfg.TruncateMode {
+	case "head", "head-tail", "bytes":
+	default:
+		return nil, fmt.Errorf("invalid --truncate-mode %q (want head, head-tail or bytes)", cfg.TruncateMode)
+	}
+	if truncateBytes != "" {
+		n, err := ParseSize(truncateBytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --truncate-bytes: %w", err)
+		}
+		cfg.TruncateBytes = n
+	}
+	for _, v := range maxSizes {
+		if err := cfg.addMaxFileSize(v); err != nil {
+			return nil, fmt.Errorf("invalid --max-file-size %q: %w", v, err)
+		}
+	}
+
+	if cfg.Verbose && cfg.Quiet {
+		return nil, errors.New("--verbose and --quiet cannot be used together")
+	}
+
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		return nil, fmt.Errorf("invalid --log-format %q (want text or json)", cfg.LogFormat)
+	}
+
+	if cfg.DiffStyle != "inline" && cfg.DiffStyle != "combined" {
+		return nil, fmt.Errorf("invalid --diff-style %q (want inline or combined)", cfg.DiffStyle)
+	}
 
 	return cfg, nil
 }
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// sizeUnits は ParseSize が受け付ける単位です（1024 の累乗）。
+var sizeUnits = []struct {
+	suffix string
+	scale  int64
+}{
+	{"GB", 1 << 30}, {"G", 1 << 30},
+	{"MB", 1 << 20}, {"M", 1 << 20},
+	{"KB", 1 << 10}, {"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize は "500KB" や "1.5MB" のようなサイズ指定をバイト数に変換します。
+// 単位は 1024 の累乗で、大文字・小文字は区別しません。単位がない場合はバイトです。
+func ParseSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	scale := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(v, u.suffix) {
+			v = strings.TrimSpace(strings.TrimSuffix(v, u.suffix))
+			scale = u.scale
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q (want a number with an optional KB, MB or GB suffix)", s)
+	}
+	return int64(n * float64(scale)), nil
+}
+
+// addMaxFileSize は --max-file-size の値を解釈します。
+// "SIZE" は全体の閾値、"PATTERN=SIZE"（または "PATTERN:SIZE"）はパターンに一致するファイルの閾値です。
+// 閾値は 1 バイト以上である必要があります（0 は指定なしの意味と区別できないため受け付けません）。
+func (c *Config) addMaxFileSize(v string) error {
+	pattern, size, found := strings.Cut(v, "=")
+	if !found {
+		if i := strings.LastIndex(v, ":"); i >= 0 {
+			pattern, size, found = v[:i], v[i+1:], true
+		}
+	}
+	if !found {
+		size = v
+	}
+
+	n, err := ParseSize(size)
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return fmt.Errorf("size %q must be at least 1 byte", strings.TrimSpace(size))
+	}
+	if !found {
+		c.MaxFileSize = n
+		return nil
+	}
+
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return errors.New("empty pattern")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	c.SizeOverrides = append(c.SizeOverrides, SizeOverride{Pattern: pattern, Size: n})
+	return nil
+}
@@ -0,0 +1,26 @@
+package config
+
+import "testing"
+
+func TestAddMaxFileSize(t *testing.T) {
+	var c Config
+	for _, v := range []string{"2MB", "*.sql=1.5M", "testdata/**:800kb"} {
+		if err := c.addMaxFileSize(v); err != nil {
+			t.Fatalf("addMaxFileSize(%q): %v", v, err)
+		}
+	}
+	if c.MaxFileSize != 2<<20 {
+		t.Errorf("MaxFileSize = %d, want %d", c.MaxFileSize, 2<<20)
+	}
+	want := []SizeOverride{{Pattern: "*.sql", Size: 3 << 19}, {Pattern: "testdata/**", Size: 800 << 10}}
+	if len(c.SizeOverrides) != len(want) || c.SizeOverrides[0] != want[0] || c.SizeOverrides[1] != want[1] {
+		t.Errorf("SizeOverrides = %+v, want %+v", c.SizeOverrides, want)
+	}
+
+	// 0 は指定なし（デフォルト）と区別できないため受け付けない
+	for _, v := range []string{"0", "0KB", "0.1B", "*.sql=0", "-1MB", "=2MB", "big"} {
+		if err := (&Config{}).addMaxFileSize(v); err == nil {
+			t.Errorf("addMaxFileSize(%q) succeeded, want an error", v)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Diff は `git diff <ref>` の結果をファイル単位のパッチに分割して保持します。
+// 差分はレビュー対象の変更量に比例する程度のサイズであるため、
+// ファイル本体とは異なりオンメモリで保持します。
+type Diff struct {
+	ref     string
+	paths   []string          // 出現順のパス一覧
+	patches map[string]string // Key: スラッシュ区切りの相対パス
+}
+
+// LoadDiff は dir をカレントディレクトリとして `git diff <ref> [<rev>]` を実行し、結果を解析します。
+// rev が空の場合はワーキングツリーとの差分を取得します。
+// --relative を指定するため、パスは dir からの相対パスになります。
+func LoadDiff(ctx context.Context, dir, ref, rev string) (*Diff, error) {
+	args := []string{
+		"-c", "core.quotePath=false",
+		"diff", "--no-color", "--no-ext-diff", "--relative", ref,
+	}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	cmd := exec.CommandContext(ctx, "git", append(args, "--")...)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("git diff %s: %w: %s", ref, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return parseDiff(ref, out), nil
+}
+
+// Ref は差分の比較元リビジョンを返します。
+func (d *Diff) Ref() string {
+	return d.ref
+}
+
+// Patch は指定パスのパッチ（`diff --git` 行から次のファイルの直前まで）を返します。
+func (d *Diff) Patch(path string) (string, bool) {
+	p, ok := d.patches[path]
+	return p, ok
+}
+
+// Paths は差分を持つファイルのパスを git の出力順で返します。
+func (d *Diff) Paths() []string {
+	return d.paths
+}
+
+// parseDiff は `git diff` の出力を `diff --git` 行ごとに分割します。
+// ハンクヘッダー(@@ ... @@)は行番号の参照に必要なため、そのまま保持します。
+// 出力は全てメモリ上にあるため、行の長さ（minify 済みのファイルなど）に上限はありません。
+func parseDiff(ref string, data []byte) *Diff {
+	d := &Diff{
+		ref:     ref,
+		patches: make(map[string]string),
+	}
+
+	var (
+		current strings.Builder
+		path    string
+		inFile  bool
+	)
+	flush := func() {
+		if inFile && path != "" {
+			if _, exists := d.patches[path]; !exists {
+				d.paths = append(d.paths, path)
+			}
+			d.patches[path] = current.String()
+		}
+		current.Reset()
+		path = ""
+		inFile = false
+	}
+
+	inHunk := false
+	for len(data) > 0 {
+		var raw []byte
+		raw, data, _ = bytes.Cut(data, []byte("\n"))
+		line := strings.TrimSuffix(string(raw), "\r")
+
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			path = pathFromDiffHeader(line)
+			inFile = true
+			inHunk = false
+		} else if !inHunk {
+			// ヘッダー部の情報でパスを確定させる（リネームや削除に対応）
+			switch {
+			case strings.HasPrefix(line, "@@"):
+				inHunk = true
+			case strings.HasPrefix(line, "rename to "):
+				if p, ok := unquotePath(strings.TrimPrefix(line, "rename to ")); ok {
+					path = p
+				}
+			case strings.HasPrefix(line, "+++ "):
+				if p, ok := unquotePath(strings.TrimSuffix(strings.TrimPrefix(line, "+++ "), "\t")); ok {
+					if p, ok := strings.CutPrefix(p, "b/"); ok {
+						path = p
+					}
+				}
+			}
+		}
+
+		if inFile {
+			current.WriteString(line)
+			current.WriteByte('\n')
+		}
+	}
+	flush()
+
+	return d
+}
+
+// pathFromDiffHeader は "diff --git a/<path> b/<path>" から b/ 側のパスを取り出します。
+// 引用符付きのパス（"a/\343\203\211..." のように、git が制御文字や非 ASCII 文字をエスケープしたもの）にも対応します。
+// 引用符がない場合、リネームでない限り a/ と b/ のパスは同一であるため、空白を含むパスでも長さから分割できます。
+func pathFromDiffHeader(line string) string {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if strings.HasPrefix(rest, `"`) || strings.HasSuffix(rest, `"`) {
+		return quotedHeaderPath(rest)
+	}
+	if !strings.HasPrefix(rest, "a/") {
+		return ""
+	}
+	// "a/<p> b/<p>" の長さは 2*len(p)+5
+	n := (len(rest) - 5) / 2
+	if n <= 0 || len(rest) < n+5 {
+		return ""
+	}
+	return rest[len(rest)-n:]
+}
+
+// quotedHeaderPath は a/ 側・b/ 側の少なくとも一方が引用符付きのヘッダーから、b/ 側のパスを取り出します。
+func quotedHeaderPath(rest string) string {
+	var b string
+	if strings.HasPrefix(rest, `"`) {
+		end := quotedLen(rest)
+		if end < 0 {
+			return ""
+		}
+		b = strings.TrimPrefix(rest[end:], " ")
+	} else {
+		// a/ 側のみ引用符がない場合、b/ 側は最後の引用符付きの文字列
+		i := strings.LastIndex(rest, ` "`)
+		if i < 0 {
+			return ""
+		}
+		b = rest[i+1:]
+	}
+	p, ok := unquotePath(b)
+	if !ok {
+		return ""
+	}
+	p, ok = strings.CutPrefix(p, "b/")
+	if !ok {
+		return ""
+	}
+	return p
+}
+
+// quotedLen は s の先頭の引用符付きの文字列の長さ（閉じる引用符を含む）を返します（閉じていない場合は -1）。
+func quotedLen(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// unquotePath は git が C 言語の形式で引用符付きにしたパス（core.quotePath）を元に戻します。
+// 引用符がない場合はそのまま返します。\343 のような8進数のエスケープは1バイトを表します。
+func unquotePath(s string) (string, bool) {
+	if !strings.HasPrefix(s, `"`) {
+		return s, true
+	}
+	p, err := strconv.Unquote(s)
+	if err != nil {
+		return "", false
+	}
+	return p, true
+}
@@ -0,0 +1,133 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDiff(t *testing.T) {
+	tests := []struct {
+		name  string
+		diff  string
+		paths []string
+	}{
+		{
+			name:  "modified",
+			diff:  "diff --git a/main.go b/main.go\nindex 1..2 100644\n--- a/main.go\n+++ b/main.go\n@@ -1 +1,2 @@\n a\n+b\n",
+			paths: []string{"main.go"},
+		},
+		{
+			// 空白を含むパスは引用符なしで出力され、--- / +++ 行の末尾にタブが付く
+			name:  "space",
+			diff:  "diff --git a/sp ace.txt b/sp ace.txt\nindex 1..2 100644\n--- a/sp ace.txt\t\n+++ b/sp ace.txt\t\n@@ -1 +1,2 @@\n a\n+b\n",
+			paths: []string{"sp ace.txt"},
+		},
+		{
+			// core.quotePath が true（git のデフォルト）の場合、非 ASCII のパスは8進数でエスケープされる
+			name: "quoted non-ascii",
+			diff: `diff --git "a/\343\203\211\343\202\255\343\203\245\343\203\241\343\203\263\343\203\210/\350\250\255\350\250\210.md" "b/\343\203\211\343\202\255\343\203\245\343\203\241\343\203\263\343\203\210/\350\250\255\350\250\210.md"
+index 1..2 100644
+--- "a/\343\203\211\343\202\255\343\203\245\343\203\241\343\203\263\343\203\210/\350\250\255\350\250\210.md"
++++ "b/\343\203\211\343\202\255\343\203\245\343\203\241\343\203\263\343\203\210/\350\250\255\350\250\210.md"
+@@ -1 +1,2 @@
+ a
++b
+`,
+			paths: []string{"ドキュメント/設計.md"},
+		},
+		{
+			// " は core.quotePath=false でも引用符付きになる
+			name:  "quoted quote",
+			diff:  "diff --git \"a/q\\\"uote.txt\" \"b/q\\\"uote.txt\"\nindex 1..2 100644\n--- \"a/q\\\"uote.txt\"\n+++ \"b/q\\\"uote.txt\"\n@@ -1 +1,2 @@\n a\n+b\n",
+			paths: []string{`q"uote.txt`},
+		},
+		{
+			name:  "deleted",
+			diff:  "diff --git \"a/\\346\\227\\247.txt\" \"b/\\346\\227\\247.txt\"\ndeleted file mode 100644\nindex 1..0\n--- \"a/\\346\\227\\247.txt\"\n+++ /dev/null\n@@ -1 +0,0 @@\n-a\n",
+			paths: []string{"旧.txt"},
+		},
+		{
+			name: "renamed",
+			diff: "diff --git a/old name.go b/new.go\nsimilarity index 90%\nrename from old name.go\nrename to new.go\n" +
+				"diff --git a/x.go b/x.go\n--- a/x.go\n+++ b/x.go\n@@ -1 +1 @@\n-a\n+b\n",
+			paths: []string{"new.go", "x.go"},
+		},
+		{
+			// 長い行（minify 済みのファイルなど）の後ろのファイルも失わない
+			name: "long line",
+			diff: "diff --git a/app.min.js b/app.min.js\n--- a/app.min.js\n+++ b/app.min.js\n@@ -1 +1 @@\n-" + strings.Repeat("x", 17<<20) + "\n+y\n" +
+				"diff --git a/after.go b/after.go\n--- a/after.go\n+++ b/after.go\n@@ -1 +1 @@\n-a\n+b\n",
+			paths: []string{"app.min.js", "after.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := parseDiff("HEAD", []byte(tt.diff))
+			if got := strings.Join(d.Paths(), ","); got != strings.Join(tt.paths, ",") {
+				t.Fatalf("Paths() = %q, want %q", d.Paths(), tt.paths)
+			}
+			for _, p := range tt.paths {
+				patch, ok := d.Patch(p)
+				body := strings.Contains(patch, "\n@@ ") || strings.Contains(patch, "\nrename to ")
+				if !ok || !strings.HasPrefix(patch, "diff --git ") || !body {
+					t.Errorf("Patch(%q) = %q, %v", p, patch[:min(len(patch), 80)], ok)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDiff(t *testing.T) {
+	dir := newRepo(t, map[string]string{
+		"main.go":          "package main\n",
+		"ドキュメント/設計.md":     "# 設計\n",
+		"sub/keep.txt":     "same\n",
+		"sub/changed.txt":  "old\n",
+		"removed file.txt": "gone\n",
+	})
+	for name, data := range map[string]string{
+		"main.go":          "package main\n\nfunc main() {}\n",
+		"ドキュメント/設計.md":     "# 設計\n\n追記\n",
+		"sub/changed.txt":  "new\n",
+		"removed file.txt": "",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if data == "" {
+			if err := os.Remove(p); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	d, err := LoadDiff(context.Background(), dir, "HEAD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"main.go", "removed file.txt", "sub/changed.txt", "ドキュメント/設計.md"}
+	if got := strings.Join(d.Paths(), ","); got != strings.Join(want, ",") {
+		t.Errorf("Paths() = %q, want %q", d.Paths(), want)
+	}
+	if patch, _ := d.Patch("ドキュメント/設計.md"); !strings.Contains(patch, "\n+追記\n") {
+		t.Errorf("patch of a non-ASCII path = %q", patch)
+	}
+
+	// --relative により、サブディレクトリからはそのディレクトリからの相対パスになる
+	d, err = LoadDiff(context.Background(), filepath.Join(dir, "sub"), "HEAD", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(d.Paths(), ","); got != "changed.txt" {
+		t.Errorf("Paths() in sub = %q, want [changed.txt]", d.Paths())
+	}
+
+	if _, err := LoadDiff(context.Background(), dir, "no-such-ref", ""); err == nil {
+		t.Error("LoadDiff with an unknown ref succeeded")
+	}
+}
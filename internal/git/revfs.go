@@ -0,0 +1,359 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os/exec"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RevisionFS は特定リビジョンのツリーを fs.FS として公開します。
+// ファイル内容はローカルのオブジェクトデータベースから `git cat-file --batch` で読み出すため、
+// ワーキングツリーには一切触れません。
+//
+// cat-file プロセスの出力は単一のストリームであるため、同時にオープンできるファイルは1つだけです。
+// 前のファイルを閉じずに次のファイルを開いた場合、前のファイルの残りは読み捨てられ無効になります。
+type RevisionFS struct {
+	ctx context.Context
+	dir string
+	rev string
+
+	entries  map[string]*treeEntry // Key: ルートからの相対パス（ディレクトリを含む）
+	children map[string][]string   // Key: ディレクトリパス, Value: ソート済みの子要素名
+
+	mu     sync.Mutex
+	batch  *catFileBatch
+	active *revFile
+}
+
+// treeEntry は ls-tree の1エントリ（または合成したディレクトリ）を表します。
+type treeEntry struct {
+	name string
+	oid  string
+	mode fs.FileMode
+	size int64
+}
+
+// OpenRevision は dir をカレントディレクトリとして rev のツリーを読み込みます。
+// ls-tree はカレントディレクトリ配下に限定されるため、パスは dir からの相対パスになります。
+func OpenRevision(ctx context.Context, dir, rev string) (*RevisionFS, error) {
+	cmd := exec.CommandContext(ctx, "git", "ls-tree", "-r", "-z", "--long", rev)
+	cmd.Dir = dir
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("git ls-tree %s: %w: %s", rev, err, strings.TrimSpace(stderr.String()))
+	}
+
+	f := &RevisionFS{
+		ctx:      ctx,
+		dir:      dir,
+		rev:      rev,
+		entries:  map[string]*treeEntry{".": {name: ".", mode: fs.ModeDir | 0o755}},
+		children: make(map[string][]string),
+	}
+
+	for _, record := range bytes.Split(out, []byte{0}) {
+		if len(record) == 0 {
+			continue
+		}
+		if err := f.addRecord(string(record)); err != nil {
+			return nil, err
+		}
+	}
+	for _, names := range f.children {
+		sort.Strings(names)
+	}
+
+	return f, nil
+}
+
+// addRecord は "<mode> <type> <object> <size>\t<path>" 形式の1レコードを登録します。
+func (f *RevisionFS) addRecord(record string) error {
+	meta, name, ok := strings.Cut(record, "\t")
+	if !ok {
+		return fmt.Errorf("unexpected ls-tree output: %q", record)
+	}
+	fields := strings.Fields(meta)
+	if len(fields) != 4 {
+		return fmt.Errorf("unexpected ls-tree output: %q", record)
+	}
+
+	var mode fs.FileMode
+	switch fields[0] {
+	case "100644":
+		mode = 0o644
+	case "100755":
+		mode = 0o755
+	case "120000":
+		mode = fs.ModeSymlink | 0o777
+	default:
+		// サブモジュール(160000)などのオブジェクトDBに実体がないエントリは対象外
+		return nil
+	}
+	size, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return fmt.Errorf("unexpected ls-tree size %q: %w", fields[3], err)
+	}
+
+	f.entries[name] = &treeEntry{name: path.Base(name), oid: fields[2], mode: mode, size: size}
+	f.addToParent(name)
+	return nil
+}
+
+// addToParent は親ディレクトリを必要に応じて合成し、子要素として登録します。
+func (f *RevisionFS) addToParent(name string) {
+	for name != "." {
+		parent := path.Dir(name)
+		f.children[parent] = append(f.children[parent], path.Base(name))
+		if _, exists := f.entries[parent]; exists {
+			return
+		}
+		f.entries[parent] = &treeEntry{name: path.Base(parent), mode: fs.ModeDir | 0o755}
+		name = parent
+	}
+}
+
+// Rev は対象のリビジョンを返します。
+func (f *RevisionFS) Rev() string {
+	return f.rev
+}
+
+// Open は fs.FS の実装です。
+func (f *RevisionFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	entry, ok := f.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+
+	if entry.mode.IsDir() {
+		return &revDir{fs: f, path: name, entry: entry}, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.active != nil {
+		if err := f.active.discardLocked(); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+
+	if f.batch == nil {
+		batch, err := startCatFileBatch(f.ctx, f.dir)
+		if err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		f.batch = batch
+	}
+
+	size, err := f.batch.request(entry.oid)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	file := &revFile{
+		fs:    f,
+		entry: entry,
+		body:  &io.LimitedReader{R: f.batch.out, N: size},
+	}
+	f.active = file
+	return file, nil
+}
+
+// ReadDir は fs.ReadDirFS の実装です。
+func (f *RevisionFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entry, ok := f.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !entry.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	names := f.children[name]
+	list := make([]fs.DirEntry, 0, len(names))
+	for _, child := range names {
+		list = append(list, fs.FileInfoToDirEntry(f.entries[path.Join(name, child)]))
+	}
+	return list, nil
+}
+
+// Close は cat-file プロセスを終了させます。
+// 開いたままのファイルは閉じたものとして扱い、以降の Read は fs.ErrClosed を返します。
+func (f *RevisionFS) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.active != nil {
+		f.active.closed = true
+		f.active = nil
+	}
+	if f.batch == nil {
+		return nil
+	}
+	err := f.batch.close()
+	f.batch = nil
+	return err
+}
+
+// --- fs.FileInfo の実装 ---
+
+func (e *treeEntry) Name() string       { return e.name }
+func (e *treeEntry) Size() int64        { return e.size }
+func (e *treeEntry) Mode() fs.FileMode  { return e.mode }
+func (e *treeEntry) ModTime() time.Time { return time.Time{} }
+func (e *treeEntry) IsDir() bool        { return e.mode.IsDir() }
+func (e *treeEntry) Sys() any           { return nil }
+
+// revFile はオブジェクトデータベース上の blob を読み出すファイルです。
+type revFile struct {
+	fs     *RevisionFS
+	entry  *treeEntry
+	body   *io.LimitedReader
+	closed bool
+}
+
+func (r *revFile) Stat() (fs.FileInfo, error) {
+	return r.entry, nil
+}
+
+func (r *revFile) Read(p []byte) (int, error) {
+	r.fs.mu.Lock()
+	defer r.fs.mu.Unlock()
+
+	if r.closed {
+		return 0, fs.ErrClosed
+	}
+	return r.body.Read(p)
+}
+
+func (r *revFile) Close() error {
+	r.fs.mu.Lock()
+	defer r.fs.mu.Unlock()
+
+	if r.closed {
+		return nil
+	}
+	return r.discardLocked()
+}
+
+// discardLocked は未読の内容と終端の改行を読み捨て、次の要求に備えます。
+// 呼び出し元で fs.mu を保持している必要があります。
+func (r *revFile) discardLocked() error {
+	r.closed = true
+	r.fs.active = nil
+	if r.fs.batch == nil {
+		// RevisionFS が閉じられた後は読み捨てる内容がない
+		return nil
+	}
+
+	if _, err := io.Copy(io.Discard, r.body); err != nil {
+		return err
+	}
+	// cat-file --batch は内容の後に LF を1つ出力する
+	_, err := r.fs.batch.out.ReadByte()
+	return err
+}
+
+// revDir はディレクトリを表すファイルです。
+type revDir struct {
+	fs     *RevisionFS
+	path   string
+	entry  *treeEntry
+	offset int
+}
+
+func (d *revDir) Stat() (fs.FileInfo, error) { return d.entry, nil }
+func (d *revDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: errors.New("is a directory")}
+}
+func (d *revDir) Close() error { return nil }
+
+// ReadDir は fs.ReadDirFile の実装です。
+func (d *revDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	all, err := d.fs.ReadDir(d.path)
+	if err != nil {
+		return nil, err
+	}
+	rest := all[d.offset:]
+	if n <= 0 {
+		d.offset = len(all)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
+
+// catFileBatch は常駐する `git cat-file --batch` プロセスです。
+type catFileBatch struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+func startCatFileBatch(ctx context.Context, dir string) (*catFileBatch, error) {
+	cmd := exec.CommandContext(ctx, "git", "cat-file", "--batch")
+	cmd.Dir = dir
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+
+	return &catFileBatch{cmd: cmd, in: in, out: bufio.NewReader(out)}, nil
+}
+
+// request はオブジェクトを要求し、応答ヘッダー "<oid> <type> <size>" からサイズを返します。
+func (b *catFileBatch) request(oid string) (int64, error) {
+	if _, err := io.WriteString(b.in, oid+"\n"); err != nil {
+		return 0, err
+	}
+	header, err := b.out.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		// "<oid> missing" など
+		return 0, fmt.Errorf("git cat-file: %s", strings.TrimSpace(header))
+	}
+	return strconv.ParseInt(fields[2], 10, 64)
+}
+
+func (b *catFileBatch) close() error {
+	b.in.Close()
+	return b.cmd.Wait()
+}
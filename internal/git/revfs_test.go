@@ -0,0 +1,136 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+// newRepo は files をコミットした一時的なリポジトリを作成します（git がない場合はスキップします）。
+func newRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+	dir := t.TempDir()
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "init"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	return dir
+}
+
+func TestRevisionFSCloseWithOpenFile(t *testing.T) {
+	dir := newRepo(t, map[string]string{"a.txt": "hello\n"})
+	rfs, err := OpenRevision(context.Background(), dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := rfs.Open("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rfs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// FS を閉じた後のファイルは読めず、Close は失敗しない
+	if _, err := f.Read(make([]byte, 1)); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Read after FS Close = %v, want %v", err, fs.ErrClosed)
+	}
+	if err := f.Close(); err != nil {
+		t.Errorf("Close after FS Close = %v", err)
+	}
+
+	// 閉じた後に開き直すと新しい cat-file プロセスで読める
+	f, err = rfs.Open("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rfs.Close()
+	data, err := io.ReadAll(f)
+	if err != nil || string(data) != "hello\n" {
+		t.Errorf("ReadAll after reopen = %q, %v", data, err)
+	}
+	f.Close()
+}
+
+func TestRevisionFS(t *testing.T) {
+	dir := newRepo(t, map[string]string{
+		"README.md":          "# old\n",
+		"cmd/app/main.go":    "package main\n",
+		"ドキュメント/設計.md":       "# 設計\n",
+		"sub/with space.txt": "spaced\n",
+	})
+	// ワーキングツリーの変更は読まない
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("# new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rfs, err := OpenRevision(context.Background(), dir, "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rfs.Close()
+
+	if err := fstest.TestFS(rfs, "README.md", "cmd/app/main.go", "ドキュメント/設計.md", "sub/with space.txt"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := fs.ReadFile(rfs, "README.md")
+	if err != nil || string(data) != "# old\n" {
+		t.Errorf("ReadFile(README.md) = %q, %v, want the committed content", data, err)
+	}
+
+	// 前のファイルを閉じずに次のファイルを開くと、前のファイルは無効になる
+	a, err := rfs.Open("README.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := rfs.Open("cmd/app/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Read(make([]byte, 1)); !errors.Is(err, fs.ErrClosed) {
+		t.Errorf("Read of the replaced file = %v, want %v", err, fs.ErrClosed)
+	}
+	if data, err := io.ReadAll(b); err != nil || string(data) != "package main\n" {
+		t.Errorf("ReadAll(cmd/app/main.go) = %q, %v", data, err)
+	}
+	a.Close()
+	b.Close()
+
+	// サブディレクトリからはそのディレクトリ配下のみ
+	sub, err := OpenRevision(context.Background(), filepath.Join(dir, "cmd"), "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	if _, err := fs.Stat(sub, "app/main.go"); err != nil {
+		t.Errorf("Stat(app/main.go) in cmd: %v", err)
+	}
+	if _, err := fs.Stat(sub, "README.md"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat(README.md) in cmd = %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -0,0 +1,230 @@
+package gitattr
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// 属性の状態です。値を持つ属性（attr=value）はその値になります。
+const (
+	Set   = "true"  // attr
+	Unset = "false" // -attr
+)
+
+// Attrs は1つのパスに適用される属性です。指定されていない属性は含まれません。
+type Attrs map[string]string
+
+// Bool は真偽値として扱う属性（linguist-generated など）の状態を返します。
+// attr・attr=true は true、-attr・attr=false は false、未指定の場合は ok が false です。
+func (a Attrs) Bool(name string) (value, ok bool) {
+	v, ok := a[name]
+	if !ok {
+		return false, false
+	}
+	return v != Unset, true
+}
+
+// rule は1行分のパターンと属性です。
+type rule struct {
+	dir     string // .gitattributes のあるディレクトリ（ルートの場合は空）
+	pattern string
+	attrs   []attr
+}
+
+type attr struct {
+	name  string
+	value string // Set / Unset / 値。空の場合は !attr（未指定に戻す）
+}
+
+// FileName は属性を定義するファイルの名前です。
+const FileName = ".gitattributes"
+
+// Attributes は .gitattributes のルールの集合です。
+// 後に追加したルールほど優先されます（同じファイル内では後の行、異なるファイルでは深いディレクトリのもの）。
+type Attributes struct {
+	rules  []rule
+	macros map[string][]attr
+}
+
+// LoadAll は fsys の各ディレクトリの .gitattributes を、浅いディレクトリから順に読み込みます。
+// 各ファイルのパターンはそのディレクトリからの相対パスとして扱います（git と同様に、深いディレクトリのものが優先されます）。
+// skipDir が true を返すディレクトリ（除外ルールに一致するものなど）は走査しません。
+// .gitattributes が1つもない場合は (nil, nil) を返します。
+func LoadAll(fsys fs.FS, skipDir func(dir string) bool) (*Attributes, error) {
+	a := &Attributes{macros: map[string][]attr{
+		// git の組み込みのマクロ
+		"binary": {{name: "diff", value: Unset}, {name: "merge", value: Unset}, {name: "text", value: Unset}},
+	}}
+	found := false
+	err := fs.WalkDir(fsys, ".", func(dir string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			// 読み込めないディレクトリは、ファイルの走査時にスキップとして記録される
+			return nil
+		}
+		if dir != "." && skipDir != nil && skipDir(dir) {
+			return fs.SkipDir
+		}
+		name := path.Join(dir, FileName)
+		f, err := fsys.Open(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		defer f.Close()
+
+		found = true
+		if dir == "." {
+			dir = ""
+		}
+		return a.parse(f, dir)
+	})
+	if err != nil || !found {
+		return nil, err
+	}
+	return a, nil
+}
+
+// parse は r のルールを dir のものとして追加します。
+func (a *Attributes) parse(r io.Reader, dir string) error {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		var attrs []attr
+		for _, f := range fields[1:] {
+			attrs = append(attrs, parseAttr(f))
+		}
+		// マクロの定義は git と同様にルートのファイルでのみ有効とする
+		if name, ok := strings.CutPrefix(fields[0], "[attr]"); ok {
+			if dir == "" {
+				a.macros[name] = attrs
+			}
+			continue
+		}
+		// 末尾が / のパターンはディレクトリを表し、gitattributes ではファイルに一致しない
+		if strings.HasSuffix(fields[0], "/") {
+			continue
+		}
+		a.rules = append(a.rules, rule{dir: dir, pattern: fields[0], attrs: attrs})
+	}
+	return sc.Err()
+}
+
+func parseAttr(s string) attr {
+	switch {
+	case strings.HasPrefix(s, "-"):
+		return attr{name: s[1:], value: Unset}
+	case strings.HasPrefix(s, "!"):
+		return attr{name: s[1:]}
+	}
+	if name, value, ok := strings.Cut(s, "="); ok {
+		return attr{name: name, value: value}
+	}
+	return attr{name: s, value: Set}
+}
+
+// Lookup は name（ルートからの相対パス、スラッシュ区切り）に適用される属性を返します。
+func (a *Attributes) Lookup(name string) Attrs {
+	attrs := Attrs{}
+	if a == nil {
+		return attrs
+	}
+	for _, r := range a.rules {
+		if !r.match(name) {
+			continue
+		}
+		a.apply(attrs, r.attrs)
+	}
+	return attrs
+}
+
+// apply は list の属性を attrs に反映します。設定したマクロ（binary など）は定義に展開します。
+func (a *Attributes) apply(attrs Attrs, list []attr) {
+	for _, at := range list {
+		if at.value == "" {
+			delete(attrs, at.name)
+			continue
+		}
+		attrs[at.name] = at.value
+		if macro, ok := a.macros[at.name]; ok && at.value == Set {
+			a.apply(attrs, macro)
+		}
+	}
+}
+
+// Match は export-ignore が設定されたパスを除外対象とします（ignorer.Matcher の実装）。
+// git archive と同様に、ディレクトリに設定した場合は配下の全てのファイルが除外されます。
+func (a *Attributes) Match(name string, isDir bool) bool {
+	v, _ := a.Lookup(name).Bool("export-ignore")
+	return v
+}
+
+// Language は linguist-language による言語の指定を返します（language.Mapper の上書きに使用します）。
+// 指定されていない場合は ok が false です。
+// linguist-detectable は言語の統計から除外する指定にすぎないため、言語の判定には使用しません。
+func (a *Attributes) Language(name string) (lang string, ok bool) {
+	if v, ok := a.Lookup(name)["linguist-language"]; ok && v != Set && v != Unset {
+		return v, true
+	}
+	return "", false
+}
+
+// Binary は diff 属性によるバイナリの指定を返します（binary マクロは -diff -merge -text です）。
+// -diff ならバイナリ、diff ならテキストとし、いずれでもない場合は ok が false です。
+// text 属性は改行コードの変換の指定であり（* text eol=lf のように全ファイルに設定されることも多い）、根拠にしません。
+func (a *Attributes) Binary(name string) (binary, ok bool) {
+	switch a.Lookup(name)["diff"] {
+	case Unset:
+		return true, true
+	case Set:
+		return false, true
+	}
+	return false, false
+}
+
+// match は name が rule のパターンに一致するかを返します。
+// / を含まないパターンはルールのディレクトリ以下の任意の階層のファイル名と、含むパターンはディレクトリからの相対パスと照合します。
+func (r rule) match(name string) bool {
+	rel := name
+	if r.dir != "" {
+		if !strings.HasPrefix(name, r.dir+"/") {
+			return false
+		}
+		rel = name[len(r.dir)+1:]
+	}
+	if !strings.Contains(r.pattern, "/") {
+		ok, _ := path.Match(r.pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(r.pattern, "/"), "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments はパスの要素ごとに照合します。"**" は0個以上の要素に一致します。
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
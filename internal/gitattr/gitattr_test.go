@@ -0,0 +1,49 @@
+package gitattr
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLanguageAndBinary(t *testing.T) {
+	fsys := fstest.MapFS{
+		FileName: {Data: []byte(`* text eol=lf
+*.png binary
+*.lock -diff
+*.dat diff
+*.h linguist-language=C++
+vendor/** -linguist-detectable
+`)},
+	}
+	a, err := LoadAll(fsys, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		lang     string
+		langOK   bool
+		binary   bool
+		binaryOK bool
+	}{
+		// text は改行コードの指定のため、バイナリの判定には使わない
+		{name: "main.go"},
+		{name: "logo.png", binary: true, binaryOK: true},
+		{name: "go.lock", binary: true, binaryOK: true},
+		{name: "blob.dat", binary: false, binaryOK: true},
+		{name: "a.h", lang: "C++", langOK: true},
+		// -linguist-detectable は言語の統計から除外するのみで、言語は拡張子で判定する
+		{name: "vendor/lib.go"},
+	}
+	for _, tt := range tests {
+		lang, ok := a.Language(tt.name)
+		if lang != tt.lang || ok != tt.langOK {
+			t.Errorf("Language(%s) = (%q, %v), want (%q, %v)", tt.name, lang, ok, tt.lang, tt.langOK)
+		}
+		binary, ok := a.Binary(tt.name)
+		if binary != tt.binary || ok != tt.binaryOK {
+			t.Errorf("Binary(%s) = (%v, %v), want (%v, %v)", tt.name, binary, ok, tt.binary, tt.binaryOK)
+		}
+	}
+}
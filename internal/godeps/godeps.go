@@ -0,0 +1,397 @@
+// Package godeps は Go のパッケージから import を辿り、同一モジュール（go.work のワークスペースを含む）内の
+// 依存パッケージを求めます。ネットワークやモジュールキャッシュは使用せず、走査対象の fs.FS のみを参照します。
+package godeps
+
+import (
+	"errors"
+	"fmt"
+	"go/build"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Package は詰め込む対象となったローカルのパッケージです。
+type Package struct {
+	ImportPath string
+	Dir        string   // fsys のルートからのディレクトリ
+	Files      []string // fsys のルートからのパス（テストを除くビルド対象のファイル）
+}
+
+// Graph は起点のパッケージから辿った依存関係です。
+// Matcher として、含めるファイル以外を除外します（ignorer.Matcher を実装します）。
+type Graph struct {
+	Start      string
+	Packages   []Package // インポートパス順
+	Stdlib     []string  // 標準ライブラリのパッケージ
+	External   []string  // 外部モジュール（"パス バージョン"。go.mod に記載がない場合はインポートパス）
+	Unresolved []string  // ローカルのモジュールに属するが、パッケージが見つからなかったインポートパス
+
+	files map[string]bool
+	dirs  map[string]bool // 含めるファイルの祖先ディレクトリ
+}
+
+// Resolve は fsys 内のディレクトリ start（"./cmd/server" など。ローカルのモジュールのインポートパスも可）の
+// パッケージを起点に、同一モジュール・ワークスペース内で推移的に import されるパッケージを求めます。
+// ビルド制約は実行中の GOOS/GOARCH で評価し、テストファイルは含めません。
+func Resolve(fsys fs.FS, start string) (*Graph, error) {
+	dir, ok := inside(".", strings.TrimSuffix(start, "/"))
+	if !ok {
+		return nil, fmt.Errorf("%s: outside the source", start)
+	}
+	r, err := newResolver(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", start, err)
+	}
+
+	if _, err := fs.Stat(fsys, dir); err != nil {
+		d, ok := r.localDir(start)
+		if !ok {
+			return nil, fmt.Errorf("%s: no such package directory", start)
+		}
+		dir = d
+	}
+	if err := r.visit(dir); err != nil {
+		return nil, err
+	}
+	return r.graph(start), nil
+}
+
+// newResolver は dir を含むモジュールと、その go.work のワークスペースのモジュールを読み込みます。
+// dir がインポートパスなどでディレクトリとして存在しない場合は、ルートのモジュール・ワークスペースを使用します。
+func newResolver(fsys fs.FS, dir string) (*resolver, error) {
+	r := &resolver{
+		fsys:     fsys,
+		ctxt:     buildContext(fsys),
+		modules:  map[string]*module{},
+		packages: map[string]*Package{},
+		stdlib:   map[string]bool{},
+		external: map[string]bool{},
+		missing:  map[string]bool{},
+		used:     map[*module]bool{},
+	}
+
+	modDir, ok := findUp(fsys, dir, "go.mod")
+	if !ok {
+		modDir, ok = findUp(fsys, ".", "go.mod")
+	}
+	workFrom := dir
+	if ok {
+		if err := r.addModule(modDir); err != nil {
+			return nil, err
+		}
+		workFrom = modDir
+	}
+	if workDir, ok := findUp(fsys, workFrom, "go.work"); ok {
+		uses, err := loadWorkspace(fsys, workDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range uses {
+			if d, ok := inside(workDir, u); ok {
+				if err := r.addModule(d); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if len(r.modules) == 0 {
+		return nil, errors.New("no go.mod or go.work found")
+	}
+	return r, nil
+}
+
+// resolver は Resolve の作業状態です。
+type resolver struct {
+	fsys    fs.FS
+	ctxt    *build.Context
+	modules map[string]*module // モジュールパス → モジュール
+
+	packages map[string]*Package // ディレクトリ → パッケージ
+	stdlib   map[string]bool
+	external map[string]bool
+	missing  map[string]bool
+	used     map[*module]bool // パッケージを含めたモジュール（go.mod も含める）
+}
+
+// buildContext は fsys のみを参照する go/build のコンテキストを返します。
+// GOROOT・GOPATH を空にし、ローカルのディレクトリの解析にのみ使用します。
+func buildContext(fsys fs.FS) *build.Context {
+	ctxt := build.Default
+	ctxt.GOROOT = ""
+	ctxt.GOPATH = ""
+	ctxt.JoinPath = path.Join
+	ctxt.IsAbsPath = func(string) bool { return false }
+	ctxt.HasSubdir = func(string, string) (string, bool) { return "", false }
+	ctxt.IsDir = func(name string) bool {
+		info, err := fs.Stat(fsys, name)
+		return err == nil && info.IsDir()
+	}
+	ctxt.ReadDir = func(dir string) ([]fs.FileInfo, error) {
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			return nil, err
+		}
+		infos := make([]fs.FileInfo, 0, len(entries))
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil {
+				infos = append(infos, info)
+			}
+		}
+		return infos, nil
+	}
+	ctxt.OpenFile = func(name string) (io.ReadCloser, error) {
+		return fsys.Open(name)
+	}
+	return &ctxt
+}
+
+// addModule は dir のモジュールと、そのローカルの replace 先を登録します。
+func (r *resolver) addModule(dir string) error {
+	m, err := loadModule(r.fsys, dir)
+	if err != nil {
+		return err
+	}
+	if _, ok := r.modules[m.path]; ok {
+		return nil
+	}
+	r.modules[m.path] = m
+	for modPath, rel := range m.replaces {
+		d, ok := inside(dir, rel)
+		if !ok {
+			continue
+		}
+		rm, err := loadModule(r.fsys, d)
+		if err != nil {
+			continue // 置き換え先がソース外にある場合などは外部モジュールとして扱う
+		}
+		rm.path = modPath
+		if _, ok := r.modules[modPath]; !ok {
+			r.modules[modPath] = rm
+		}
+	}
+	return nil
+}
+
+// moduleOf はインポートパスが属するローカルのモジュールを返します（最長一致）。
+func (r *resolver) moduleOf(importPath string) *module {
+	var found *module
+	for p, m := range r.modules {
+		if importPath == p || strings.HasPrefix(importPath, p+"/") {
+			if found == nil || len(p) > len(found.path) {
+				found = m
+			}
+		}
+	}
+	return found
+}
+
+// localDir はローカルのモジュールに属するインポートパスのディレクトリを返します。
+func (r *resolver) localDir(importPath string) (string, bool) {
+	m := r.moduleOf(importPath)
+	if m == nil {
+		return "", false
+	}
+	return path.Join(m.dir, strings.TrimPrefix(importPath, m.path)), true
+}
+
+// importPath はローカルのディレクトリのインポートパスを返します。
+func (r *resolver) importPath(dir string) (string, *module) {
+	var (
+		found *module
+		sub   string
+	)
+	for _, m := range r.modules {
+		if rel, ok := within(dir, m.dir); ok && (found == nil || len(m.dir) > len(found.dir)) {
+			found, sub = m, rel
+		}
+	}
+	if found == nil {
+		return dir, nil
+	}
+	return path.Join(found.path, sub), found
+}
+
+// within は dir が root 以下にある場合に、root からの相対パスを返します。
+func within(dir, root string) (string, bool) {
+	switch {
+	case dir == root:
+		return "", true
+	case root == ".":
+		return dir, true
+	case strings.HasPrefix(dir, root+"/"):
+		return dir[len(root)+1:], true
+	}
+	return "", false
+}
+
+// visit は dir のパッケージを登録し、その import を辿ります。
+func (r *resolver) visit(dir string) error {
+	if _, ok := r.packages[dir]; ok {
+		return nil
+	}
+
+	bp, err := r.ctxt.ImportDir(dir, 0)
+	if err != nil {
+		return fmt.Errorf("%s: %w", dir, err)
+	}
+
+	ip, m := r.importPath(dir)
+	pkg := &Package{ImportPath: ip, Dir: dir}
+	for _, group := range [][]string{bp.GoFiles, bp.CgoFiles, bp.CFiles, bp.CXXFiles, bp.HFiles, bp.SFiles} {
+		for _, f := range group {
+			pkg.Files = append(pkg.Files, path.Join(dir, f))
+		}
+	}
+	pkg.Files = append(pkg.Files, r.embedFiles(dir, bp.EmbedPatterns)...)
+	r.packages[dir] = pkg
+	if m != nil {
+		r.used[m] = true
+	}
+
+	for _, imp := range bp.Imports {
+		switch {
+		case imp == "C":
+			// cgo の疑似パッケージ
+		case r.moduleOf(imp) != nil:
+			d, _ := r.localDir(imp)
+			if err := r.visit(d); err != nil {
+				r.missing[imp] = true
+			}
+		case isStdlib(imp):
+			r.stdlib[imp] = true
+		default:
+			r.external[r.externalModule(imp)] = true
+		}
+	}
+	return nil
+}
+
+// embedFiles は //go:embed のパターンに一致するファイルを返します。
+// ディレクトリに一致した場合は、go:embed と同様に . と _ で始まるものを除いた配下のファイルを含めます。
+func (r *resolver) embedFiles(dir string, patterns []string) []string {
+	var files []string
+	for _, pat := range patterns {
+		all := strings.HasPrefix(pat, "all:")
+		matches, _ := fs.Glob(r.fsys, path.Join(dir, strings.TrimPrefix(pat, "all:")))
+		for _, m := range matches {
+			fs.WalkDir(r.fsys, m, func(name string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return nil
+				}
+				if base := path.Base(name); name != m && !all && (strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_")) {
+					if d.IsDir() {
+						return fs.SkipDir
+					}
+					return nil
+				}
+				if !d.IsDir() {
+					files = append(files, name)
+				}
+				return nil
+			})
+		}
+	}
+	return files
+}
+
+// externalModule は外部のインポートパスを、go.mod に記載されたモジュールとバージョンに置き換えます。
+func (r *resolver) externalModule(importPath string) string {
+	best, version := "", ""
+	for _, m := range r.modules {
+		for p, v := range m.requires {
+			if (importPath == p || strings.HasPrefix(importPath, p+"/")) && len(p) > len(best) {
+				best, version = p, v
+			}
+		}
+	}
+	if best == "" {
+		return importPath
+	}
+	return best + " " + version
+}
+
+// isStdlib は標準ライブラリのインポートパスかを返します（最初の要素にドットを含まないもの）。
+func isStdlib(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// graph は解決結果を整列して Graph にまとめます。
+func (r *resolver) graph(start string) *Graph {
+	g := &Graph{
+		Start:      start,
+		Stdlib:     sortedKeys(r.stdlib),
+		External:   sortedKeys(r.external),
+		Unresolved: sortedKeys(r.missing),
+		files:      map[string]bool{},
+		dirs:       map[string]bool{".": true},
+	}
+	for _, pkg := range r.packages {
+		g.Packages = append(g.Packages, *pkg)
+		for _, f := range pkg.Files {
+			g.addFile(f)
+		}
+	}
+	sort.Slice(g.Packages, func(i, j int) bool { return g.Packages[i].ImportPath < g.Packages[j].ImportPath })
+
+	// モジュールの定義も依存関係の前提として含める
+	for m := range r.used {
+		g.addFile(path.Join(m.dir, "go.mod"))
+	}
+	if workDir, ok := findUp(r.fsys, ".", "go.work"); ok {
+		g.addFile(path.Join(workDir, "go.work"))
+	}
+	return g
+}
+
+func (g *Graph) addFile(name string) {
+	g.files[name] = true
+	for d := path.Dir(name); d != "."; d = path.Dir(d) {
+		g.dirs[d] = true
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Match は依存関係に含まれないファイル（と、それらを含まないディレクトリ）を除外対象とします。
+func (g *Graph) Match(name string, isDir bool) bool {
+	if isDir {
+		return !g.dirs[name]
+	}
+	return !g.files[name]
+}
+
+// Markdown は詰め込んだパッケージと、詰め込まなかった依存（標準ライブラリ・外部モジュール）の一覧を返します。
+func (g *Graph) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n## Go dependencies of %s\n", g.Start)
+
+	fmt.Fprintf(&b, "\nPackages (%d, packed):\n\n", len(g.Packages))
+	for _, p := range g.Packages {
+		fmt.Fprintf(&b, "- %s (%s)\n", p.ImportPath, p.Dir)
+	}
+	writeList(&b, "Standard library (%d, not packed):", g.Stdlib)
+	writeList(&b, "External modules (%d, not packed):", g.External)
+	writeList(&b, "Unresolved local imports (%d):", g.Unresolved)
+	return b.String()
+}
+
+func writeList(b *strings.Builder, heading string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n"+heading+"\n\n", len(items))
+	for _, it := range items {
+		fmt.Fprintf(b, "- %s\n", it)
+	}
+}
@@ -0,0 +1,147 @@
+package godeps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// workspace は go.work で2つのモジュールを束ね、replace でソース内の別モジュールを参照するツリーです。
+var workspace = fstest.MapFS{
+	"go.work": {Data: []byte("go 1.22\n\nuse (\n\t./app\n\t./lib\n)\n")},
+
+	"app/go.mod": {Data: []byte("module example.com/app\n\ngo 1.22\n\nrequire github.com/x/y v1.2.3\n\nreplace example.com/shared => ../shared\n")},
+	"app/cmd/server/main.go": {Data: []byte(`package main
+
+import (
+	"fmt"
+
+	"example.com/app/internal/store"
+	"example.com/lib/util"
+	"github.com/x/y/z"
+)
+
+func main() { fmt.Println(store.Open(), util.Upper("a"), z.Z) }
+`)},
+	"app/cmd/server/main_test.go": {Data: []byte("package main\n")},
+	"app/cmd/server/gen.go":       {Data: []byte("//go:build ignore\n\npackage main\n")},
+	"app/internal/store/store.go": {Data: []byte(`package store
+
+import (
+	"embed"
+
+	"example.com/app/missing"
+	"example.com/shared"
+)
+
+//go:embed schema
+var schema embed.FS
+
+func Open() string { return shared.Name + missing.X }
+`)},
+	"app/internal/store/schema/a.sql":   {Data: []byte("create table a;\n")},
+	"app/internal/store/schema/.hidden": {Data: []byte("x\n")},
+	"app/internal/unused/unused.go":     {Data: []byte("package unused\n")},
+
+	"lib/go.mod":       {Data: []byte("module example.com/lib\n")},
+	"lib/util/util.go": {Data: []byte("package util\n\nimport \"strings\"\n\nfunc Upper(s string) string { return strings.ToUpper(s) }\n")},
+
+	"shared/go.mod":    {Data: []byte("module example.com/shared\n")},
+	"shared/shared.go": {Data: []byte("package shared\n\nconst Name = \"shared\"\n")},
+}
+
+func TestResolve(t *testing.T) {
+	// ディレクトリでもインポートパスでも同じ結果になる
+	for _, start := range []string{"./app/cmd/server", "app/cmd/server/", "example.com/app/cmd/server"} {
+		t.Run(start, func(t *testing.T) {
+			g, err := Resolve(workspace, start)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var pkgs []string
+			for _, p := range g.Packages {
+				pkgs = append(pkgs, p.ImportPath+" "+p.Dir)
+			}
+			wantPkgs := []string{
+				"example.com/app/cmd/server app/cmd/server",
+				"example.com/app/internal/store app/internal/store",
+				"example.com/lib/util lib/util",
+				"example.com/shared shared",
+			}
+			if !reflect.DeepEqual(pkgs, wantPkgs) {
+				t.Errorf("Packages = %q, want %q", pkgs, wantPkgs)
+			}
+			if want := []string{"embed", "fmt", "strings"}; !reflect.DeepEqual(g.Stdlib, want) {
+				t.Errorf("Stdlib = %q, want %q", g.Stdlib, want)
+			}
+			if want := []string{"github.com/x/y v1.2.3"}; !reflect.DeepEqual(g.External, want) {
+				t.Errorf("External = %q, want %q", g.External, want)
+			}
+			if want := []string{"example.com/app/missing"}; !reflect.DeepEqual(g.Unresolved, want) {
+				t.Errorf("Unresolved = %q, want %q", g.Unresolved, want)
+			}
+
+			// Match は含めないものに true を返す
+			for name, excluded := range map[string]bool{
+				"go.work":                           false,
+				"app/go.mod":                        false,
+				"lib/go.mod":                        false,
+				"shared/go.mod":                     false,
+				"app/cmd/server/main.go":            false,
+				"app/cmd/server/main_test.go":       true,
+				"app/cmd/server/gen.go":             true,
+				"app/internal/store/schema/a.sql":   false,
+				"app/internal/store/schema/.hidden": true,
+				"app/internal/unused/unused.go":     true,
+			} {
+				if got := g.Match(name, false); got != excluded {
+					t.Errorf("Match(%s) = %v, want %v", name, got, excluded)
+				}
+			}
+			if g.Match("app/internal", true) || !g.Match("app/internal/unused", true) {
+				t.Error("Match excluded a directory on the path to a packed file, or kept an unrelated one")
+			}
+		})
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		start string
+		want  string
+	}{
+		{"../outside", "outside the source"},
+		{"example.com/app/nothing", "cannot find package"},
+		{"github.com/x/y", "no such package directory"},
+	}
+	for _, tt := range tests {
+		if _, err := Resolve(workspace, tt.start); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Resolve(%s) = %v, want an error containing %q", tt.start, err, tt.want)
+		}
+	}
+
+	if _, err := Resolve(fstest.MapFS{"main.go": {Data: []byte("package main\n")}}, "."); err == nil || !strings.Contains(err.Error(), "no go.mod or go.work") {
+		t.Errorf("Resolve without go.mod = %v", err)
+	}
+}
+
+func TestGraphMarkdown(t *testing.T) {
+	g, err := Resolve(workspace, "./app/cmd/server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := g.Markdown()
+	for _, want := range []string{
+		"\n## Go dependencies of ./app/cmd/server\n",
+		"\nPackages (4, packed):\n\n- example.com/app/cmd/server (app/cmd/server)\n",
+		"\nStandard library (3, not packed):\n\n- embed\n- fmt\n- strings\n",
+		"\nExternal modules (1, not packed):\n\n- github.com/x/y v1.2.3\n",
+		"\nUnresolved local imports (1):\n\n- example.com/app/missing\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Markdown does not contain %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package godeps
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// module はソース内にあるモジュール（go.mod のあるディレクトリ）です。
+type module struct {
+	path     string            // モジュールパス
+	dir      string            // fsys のルートからのディレクトリ
+	requires map[string]string // 依存モジュールのパスとバージョン
+	replaces map[string]string // ローカルのディレクトリへの置き換え（モジュールパス → go.mod からの相対パス）
+}
+
+// loadModule は dir の go.mod を読み込みます。
+func loadModule(fsys fs.FS, dir string) (*module, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, err
+	}
+	m := &module{dir: dir, requires: map[string]string{}, replaces: map[string]string{}}
+	for _, d := range parseDirectives(string(data)) {
+		switch d.verb {
+		case "module":
+			if len(d.args) > 0 {
+				m.path = d.args[0]
+			}
+		case "require":
+			if len(d.args) >= 2 {
+				m.requires[d.args[0]] = d.args[1]
+			}
+		case "replace":
+			// old [version] => new [version] のうち、置き換え先がローカルのディレクトリのもの
+			i := indexOf(d.args, "=>")
+			if i < 1 || i+1 >= len(d.args) {
+				continue
+			}
+			if target := d.args[i+1]; strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") {
+				m.replaces[d.args[0]] = target
+			}
+		}
+	}
+	if m.path == "" {
+		return nil, errors.New(path.Join(dir, "go.mod") + ": missing module directive")
+	}
+	return m, nil
+}
+
+// loadWorkspace は dir の go.work の use ディレクティブ（go.work からの相対ディレクトリ）を返します。
+func loadWorkspace(fsys fs.FS, dir string) ([]string, error) {
+	data, err := fs.ReadFile(fsys, path.Join(dir, "go.work"))
+	if err != nil {
+		return nil, err
+	}
+	var uses []string
+	for _, d := range parseDirectives(string(data)) {
+		if d.verb == "use" && len(d.args) > 0 {
+			uses = append(uses, d.args[0])
+		}
+	}
+	return uses, nil
+}
+
+// directive は go.mod / go.work の1行分の指定です。ブロック内の行は verb を補って返します。
+type directive struct {
+	verb string
+	args []string
+}
+
+// parseDirectives は go.mod / go.work を行ごとのディレクティブに分解します。
+// golang.org/x/mod を使わず、依存の解決に必要な module / require / replace / use のみを扱える程度の解析です。
+func parseDirectives(src string) []directive {
+	var (
+		out   []directive
+		block string // ( ... ) の途中の場合はその verb
+	)
+	for _, line := range strings.Split(src, "\n") {
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			out = append(out, directive{verb: block, args: unquoteAll(fields)})
+			continue
+		}
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+		out = append(out, directive{verb: fields[0], args: unquoteAll(fields[1:])})
+	}
+	return out
+}
+
+// unquoteAll は引用符で囲まれたパスを展開します。
+func unquoteAll(fields []string) []string {
+	for i, f := range fields {
+		if s, err := strconv.Unquote(f); err == nil {
+			fields[i] = s
+		}
+	}
+	return fields
+}
+
+func indexOf(s []string, v string) int {
+	for i, x := range s {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// findUp は dir から fsys のルートまで遡り、name を含む最も近いディレクトリを返します。
+func findUp(fsys fs.FS, dir, name string) (string, bool) {
+	for {
+		if _, err := fs.Stat(fsys, path.Join(dir, name)); err == nil {
+			return dir, true
+		}
+		if dir == "." {
+			return "", false
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// inside はディレクトリ（dir からの相対パス rel）を解決し、fsys 内に収まる場合のみ返します。
+func inside(dir, rel string) (string, bool) {
+	p := path.Join(dir, rel)
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", false
+	}
+	return p, true
+}
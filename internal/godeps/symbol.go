@@ -0,0 +1,527 @@
+package godeps
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Ref はシンボルに関係する宣言の位置です。
+type Ref struct {
+	Path  string // fsys のルートからのパス
+	Start int    // 宣言の最初の行（ドキュメントコメントを含む）
+	End   int    // 宣言の最後の行
+	Role  string // "definition" / "type" / "caller"（関数・メソッドの場合）/ "reference"（それ以外の場合）
+	Of    string // 対象のシンボルの指定
+	Decl  string // 宣言の名前（"Packer.Pack" など）
+}
+
+// Note はスニペットの見出しに添える説明を返します。
+func (r Ref) Note() string {
+	switch r.Role {
+	case "definition":
+		return "definition of " + r.Of
+	case "type":
+		return fmt.Sprintf("type %s, used by %s", r.Decl, r.Of)
+	case "reference":
+		return fmt.Sprintf("%s, refers to %s", r.Decl, r.Of)
+	}
+	return fmt.Sprintf("%s, caller of %s", r.Decl, r.Of)
+}
+
+// Location は "path:start-end"（1行の場合は "path:line"）を返します。
+func (r Ref) Location() string {
+	if r.Start == r.End {
+		return fmt.Sprintf("%s:%d", r.Path, r.Start)
+	}
+	return fmt.Sprintf("%s:%d-%d", r.Path, r.Start, r.End)
+}
+
+// Symbols は --symbol の解析結果です。Refs は定義・使用している型・呼び出し元の順に並びます（重複は除きます）。
+type Symbols struct {
+	Specs []string
+	Refs  []Ref
+}
+
+// FindSymbols は fsys のルートのモジュール（go.work のワークスペースを含む）を go/types で型検査し、
+// specs のシンボル（"pkg.Func"、"pkg.Type"、"pkg.Type.Method"）の定義、定義が直接使用しているローカルの型、
+// および直接の呼び出し元（型・変数の場合は参照している宣言）を求めます。
+// pkg はインポートパス、その末尾の要素、またはパッケージ名です。
+// 外部のパッケージは読み込まないため、型検査のエラーは無視し、解決できた範囲で求めます。
+func FindSymbols(fsys fs.FS, specs []string) (*Symbols, error) {
+	r, err := newResolver(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	c := newChecker(r)
+	if err := c.checkAll(); err != nil {
+		return nil, err
+	}
+
+	result := &Symbols{Specs: specs}
+	seen := map[string]bool{}
+	add := func(ref Ref) {
+		key := fmt.Sprintf("%s:%d", ref.Path, ref.Start)
+		if !seen[key] {
+			seen[key] = true
+			result.Refs = append(result.Refs, ref)
+		}
+	}
+	for _, spec := range specs {
+		obj, err := c.lookup(spec)
+		if err != nil {
+			return nil, err
+		}
+		def, ok := c.declOf(obj.Pos())
+		if !ok {
+			return nil, fmt.Errorf("%s: declaration not found", spec)
+		}
+		add(c.ref(def, "definition", spec))
+		for _, d := range c.typesUsed(def, obj) {
+			add(c.ref(d, "type", spec))
+		}
+		role := "reference"
+		if _, ok := obj.(*types.Func); ok {
+			role = "caller"
+		}
+		for _, d := range c.callers(obj, def) {
+			add(c.ref(d, role, spec))
+		}
+	}
+	return result, nil
+}
+
+// Markdown は宣言の一覧（ファイルと行の範囲）を返します。
+func (s *Symbols) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n## Symbols: %s\n\n", strings.Join(s.Specs, ", "))
+	for _, r := range s.Refs {
+		fmt.Fprintf(&b, "- %s (%s)\n", r.Location(), r.Note())
+	}
+	return b.String()
+}
+
+// checker はローカルのパッケージを型検査します。
+type checker struct {
+	r     *resolver
+	fset  *token.FileSet
+	dirs  map[string]*build.Package // インポートパス → パッケージ
+	order []string                  // インポートパス順
+
+	pkgs  map[string]*checkedPackage
+	fakes map[string]*types.Package // 外部のパッケージの代わり（中身を持たない）
+	files map[string]*ast.File      // fsys のパス → 構文木
+}
+
+// checkedPackage は型検査済みのパッケージです。
+type checkedPackage struct {
+	pkg   *types.Package
+	files []*ast.File
+	info  *types.Info
+	done  bool
+}
+
+// decl は宣言（ファイル内の範囲）です。
+type decl struct {
+	file       *ast.File
+	node       ast.Node
+	name       string
+	start, end token.Pos // ドキュメントコメントを含む範囲
+}
+
+func newChecker(r *resolver) *checker {
+	return &checker{
+		r:     r,
+		fset:  token.NewFileSet(),
+		dirs:  map[string]*build.Package{},
+		pkgs:  map[string]*checkedPackage{},
+		fakes: map[string]*types.Package{},
+		files: map[string]*ast.File{},
+	}
+}
+
+// checkAll はローカルのモジュール内の全パッケージを列挙し、型検査します。
+func (c *checker) checkAll() error {
+	mods := make([]*module, 0, len(c.r.modules))
+	for _, m := range c.r.modules {
+		mods = append(mods, m)
+	}
+	sort.Slice(mods, func(i, j int) bool { return mods[i].dir < mods[j].dir })
+
+	for _, m := range mods {
+		err := fs.WalkDir(c.r.fsys, m.dir, func(dir string, d fs.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if dir != m.dir {
+				name := d.Name()
+				if name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+					return fs.SkipDir
+				}
+				// 入れ子のモジュールは別のモジュールとして扱う
+				if _, err := fs.Stat(c.r.fsys, path.Join(dir, "go.mod")); err == nil {
+					return fs.SkipDir
+				}
+			}
+			bp, err := c.r.ctxt.ImportDir(dir, 0)
+			if err != nil {
+				return nil // Go のファイルがないディレクトリなど
+			}
+			ip, _ := c.r.importPath(dir)
+			if _, ok := c.dirs[ip]; !ok {
+				c.dirs[ip] = bp
+				c.order = append(c.order, ip)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	sort.Strings(c.order)
+
+	for _, ip := range c.order {
+		if _, err := c.check(ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Import は types.Importer の実装です。ローカルのパッケージは型検査し、それ以外は空のパッケージを返します。
+func (c *checker) Import(importPath string) (*types.Package, error) {
+	if _, ok := c.dirs[importPath]; ok {
+		cp, err := c.check(importPath)
+		if err != nil {
+			return nil, err
+		}
+		return cp.pkg, nil
+	}
+	if p, ok := c.fakes[importPath]; ok {
+		return p, nil
+	}
+	p := types.NewPackage(importPath, guessName(importPath))
+	p.MarkComplete()
+	c.fakes[importPath] = p
+	return p, nil
+}
+
+// guessName はインポートパスからパッケージ名を推定します（"/v2" などのメジャーバージョンは除きます）。
+func guessName(importPath string) string {
+	elems := strings.Split(importPath, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	return strings.NewReplacer("-", "_", ".", "_").Replace(name)
+}
+
+// check は importPath のパッケージを型検査します（検査済みの場合は結果を返します）。
+func (c *checker) check(importPath string) (*checkedPackage, error) {
+	if cp, ok := c.pkgs[importPath]; ok {
+		if !cp.done {
+			return nil, fmt.Errorf("import cycle through %s", importPath)
+		}
+		return cp, nil
+	}
+	cp := &checkedPackage{}
+	c.pkgs[importPath] = cp
+
+	bp := c.dirs[importPath]
+	for _, name := range append(append([]string{}, bp.GoFiles...), bp.CgoFiles...) {
+		p := path.Join(bp.Dir, name)
+		data, err := fs.ReadFile(c.r.fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		f, err := parser.ParseFile(c.fset, p, data, parser.ParseComments)
+		if f == nil {
+			return nil, err
+		}
+		cp.files = append(cp.files, f)
+		c.files[p] = f
+	}
+
+	cp.info = &types.Info{
+		Defs: map[*ast.Ident]types.Object{},
+		Uses: map[*ast.Ident]types.Object{},
+	}
+	conf := types.Config{
+		Importer:    c,
+		FakeImportC: true,
+		Error:       func(error) {}, // 外部のパッケージを読み込まないため、エラーは無視して検査を続ける
+	}
+	cp.pkg, _ = conf.Check(importPath, c.fset, cp.files, cp.info)
+	cp.done = true
+	return cp, nil
+}
+
+// lookup はシンボルの指定を型検査済みのオブジェクトに解決します。
+func (c *checker) lookup(spec string) (types.Object, error) {
+	slash := strings.LastIndex(spec, "/")
+	dot := strings.Index(spec[slash+1:], ".")
+	if dot < 0 {
+		return nil, fmt.Errorf("%s: want pkg.Name or pkg.Type.Method", spec)
+	}
+	pkgPart, rest := spec[:slash+1+dot], spec[slash+1+dot+1:]
+	rest = strings.NewReplacer("(", "", ")", "", "*", "").Replace(rest)
+	name, method, _ := strings.Cut(rest, ".")
+
+	var (
+		found      []types.Object
+		candidates []string
+	)
+	for _, ip := range c.order {
+		cp := c.pkgs[ip]
+		if ip != pkgPart && !strings.HasSuffix(ip, "/"+pkgPart) && cp.pkg.Name() != pkgPart {
+			continue
+		}
+		candidates = append(candidates, ip)
+		obj := cp.pkg.Scope().Lookup(name)
+		if obj == nil {
+			continue
+		}
+		if method != "" {
+			tn, ok := obj.(*types.TypeName)
+			if !ok {
+				continue
+			}
+			obj, _, _ = types.LookupFieldOrMethod(tn.Type(), true, cp.pkg, method)
+			if _, ok := obj.(*types.Func); !ok {
+				continue
+			}
+		}
+		found = append(found, obj)
+	}
+	switch {
+	case len(candidates) == 0:
+		return nil, fmt.Errorf("%s: no local package %q", spec, pkgPart)
+	case len(found) == 0:
+		return nil, fmt.Errorf("%s: not found in %s", spec, strings.Join(candidates, ", "))
+	case len(found) > 1:
+		paths := make([]string, len(found))
+		for i, o := range found {
+			paths[i] = o.Pkg().Path()
+		}
+		return nil, fmt.Errorf("%s: ambiguous, found in %s", spec, strings.Join(paths, ", "))
+	}
+	return found[0], nil
+}
+
+// declOf は pos を含むトップレベルの宣言を返します。
+// 括弧でまとめた type / var / const 宣言の場合は、pos を含む個々の宣言のみを範囲とします。
+func (c *checker) declOf(pos token.Pos) (decl, bool) {
+	if !pos.IsValid() {
+		return decl{}, false
+	}
+	f, ok := c.files[c.fset.Position(pos).Filename]
+	if !ok {
+		return decl{}, false
+	}
+	for _, d := range f.Decls {
+		if pos < d.Pos() || pos >= d.End() {
+			continue
+		}
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			return newDecl(f, d, d.Doc, funcName(d)), true
+		case *ast.GenDecl:
+			if !d.Lparen.IsValid() {
+				return newDecl(f, d, d.Doc, specName(d.Specs[0])), true
+			}
+			for _, s := range d.Specs {
+				if pos >= s.Pos() && pos < s.End() {
+					return newDecl(f, s, specDoc(s), specName(s)), true
+				}
+			}
+			return newDecl(f, d, d.Doc, specName(d.Specs[0])), true
+		}
+	}
+	return decl{}, false
+}
+
+func newDecl(f *ast.File, node ast.Node, doc *ast.CommentGroup, name string) decl {
+	d := decl{file: f, node: node, name: name, start: node.Pos(), end: node.End()}
+	if doc != nil {
+		d.start = doc.Pos()
+	}
+	return d
+}
+
+// funcName は関数・メソッドの名前（"Type.Method"）を返します。
+func funcName(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return d.Name.Name
+	}
+	t := d.Recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+			continue
+		case *ast.IndexExpr:
+			t = x.X
+			continue
+		case *ast.IndexListExpr:
+			t = x.X
+			continue
+		case *ast.Ident:
+			return x.Name + "." + d.Name.Name
+		}
+		return d.Name.Name
+	}
+}
+
+func specName(s ast.Spec) string {
+	switch s := s.(type) {
+	case *ast.TypeSpec:
+		return s.Name.Name
+	case *ast.ValueSpec:
+		return s.Names[0].Name
+	}
+	return ""
+}
+
+func specDoc(s ast.Spec) *ast.CommentGroup {
+	switch s := s.(type) {
+	case *ast.TypeSpec:
+		return s.Doc
+	case *ast.ValueSpec:
+		return s.Doc
+	}
+	return nil
+}
+
+// ref は宣言を Ref に変換します。
+func (c *checker) ref(d decl, role, spec string) Ref {
+	start, end := c.fset.Position(d.start), c.fset.Position(d.end)
+	return Ref{Path: start.Filename, Start: start.Line, End: end.Line, Role: role, Of: spec, Decl: d.name}
+}
+
+// infoFor は構文木のファイルを含むパッケージの型情報を返します。
+func (c *checker) infoFor(f *ast.File) *types.Info {
+	for _, cp := range c.pkgs {
+		for _, pf := range cp.files {
+			if pf == f {
+				return cp.info
+			}
+		}
+	}
+	return nil
+}
+
+// typesUsed は宣言 d が直接使用している、ローカルのパッケージのトップレベルの型の宣言を返します。
+func (c *checker) typesUsed(d decl, self types.Object) []decl {
+	info := c.infoFor(d.file)
+	if info == nil {
+		return nil
+	}
+	var (
+		out  []decl
+		seen = map[types.Object]bool{self: true}
+	)
+	ast.Inspect(d.node, func(n ast.Node) bool {
+		id, ok := n.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		tn, ok := info.Uses[id].(*types.TypeName)
+		if !ok || seen[tn] || tn.Pkg() == nil || tn.Parent() != tn.Pkg().Scope() {
+			return true // ローカル変数の型や型パラメータ、組み込みの型
+		}
+		seen[tn] = true
+		if _, local := c.dirs[tn.Pkg().Path()]; !local {
+			return true
+		}
+		if td, ok := c.declOf(tn.Pos()); ok {
+			out = append(out, td)
+		}
+		return true
+	})
+	return out
+}
+
+// callers は obj を呼び出している（関数・メソッド以外の場合は参照している）トップレベルの宣言を返します。
+// 定義自身（再帰呼び出し）は含めません。パスと行の順に並べます。
+func (c *checker) callers(obj types.Object, def decl) []decl {
+	fn, isFunc := obj.(*types.Func)
+	matches := func(o types.Object) bool {
+		if isFunc {
+			if f, ok := o.(*types.Func); ok {
+				return f.Origin() == fn
+			}
+			return false
+		}
+		return o == obj
+	}
+
+	var out []decl
+	for _, ip := range c.order {
+		cp := c.pkgs[ip]
+		for _, f := range cp.files {
+			for _, d := range f.Decls {
+				var at token.Pos
+				ast.Inspect(d, func(n ast.Node) bool {
+					if at.IsValid() {
+						return false
+					}
+					var id *ast.Ident
+					if isFunc {
+						call, ok := n.(*ast.CallExpr)
+						if !ok {
+							return true
+						}
+						id = calleeIdent(call.Fun)
+					} else {
+						id, _ = n.(*ast.Ident)
+					}
+					if id != nil && matches(cp.info.Uses[id]) {
+						at = id.Pos()
+					}
+					return true
+				})
+				if !at.IsValid() {
+					continue
+				}
+				if cd, ok := c.declOf(at); ok && cd.start != def.start {
+					out = append(out, cd)
+				}
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		pi, pj := c.fset.Position(out[i].start), c.fset.Position(out[j].start)
+		if pi.Filename != pj.Filename {
+			return pi.Filename < pj.Filename
+		}
+		return pi.Line < pj.Line
+	})
+	return out
+}
+
+// calleeIdent は呼び出し式の関数部分の識別子（f、pkg.F、x.Method、F[T] など）を返します。
+func calleeIdent(fun ast.Expr) *ast.Ident {
+	for {
+		switch x := fun.(type) {
+		case *ast.ParenExpr:
+			fun = x.X
+		case *ast.IndexExpr:
+			fun = x.X
+		case *ast.IndexListExpr:
+			fun = x.X
+		case *ast.SelectorExpr:
+			return x.Sel
+		case *ast.Ident:
+			return x
+		default:
+			return nil
+		}
+	}
+}
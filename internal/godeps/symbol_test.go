@@ -0,0 +1,125 @@
+package godeps
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+var shop = fstest.MapFS{
+	"go.mod": {Data: []byte("module example.com/shop\n\nrequire github.com/ext/lib v0.1.0\n")},
+	"shop/cart.go": {Data: []byte(`package shop
+
+// Item は商品です。
+type Item struct {
+	Price int
+}
+
+type (
+	// Cart はカートです。
+	Cart struct {
+		Items []Item
+	}
+	Total int
+)
+
+// Sum は合計を返します。
+func (c *Cart) Sum() Total {
+	var t Total
+	for _, it := range c.Items {
+		t += Total(it.Price)
+	}
+	return t
+}
+
+func sumAll(cs []*Cart) (t Total) {
+	for _, c := range cs {
+		t += c.Sum()
+	}
+	return t
+}
+`)},
+	"cmd/main.go": {Data: []byte(`package main
+
+import (
+	"fmt"
+
+	"example.com/shop/shop"
+	"github.com/ext/lib"
+)
+
+func main() {
+	c := &shop.Cart{}
+	fmt.Println(c.Sum(), lib.X)
+}
+`)},
+}
+
+func TestFindSymbols(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string // "位置 (説明)"
+	}{
+		{
+			// 外部のパッケージを読み込まなくても、解決できた範囲で求める
+			spec: "shop.Cart.Sum",
+			want: []string{
+				"shop/cart.go:16-23 (definition of shop.Cart.Sum)",
+				"shop/cart.go:9-12 (type Cart, used by shop.Cart.Sum)",
+				"shop/cart.go:13 (type Total, used by shop.Cart.Sum)",
+				"cmd/main.go:10-13 (main, caller of shop.Cart.Sum)",
+				"shop/cart.go:25-30 (sumAll, caller of shop.Cart.Sum)",
+			},
+		},
+		{
+			spec: "example.com/shop/shop.(*Cart).Sum",
+			want: []string{
+				"shop/cart.go:16-23 (definition of example.com/shop/shop.(*Cart).Sum)",
+				"shop/cart.go:9-12 (type Cart, used by example.com/shop/shop.(*Cart).Sum)",
+				"shop/cart.go:13 (type Total, used by example.com/shop/shop.(*Cart).Sum)",
+				"cmd/main.go:10-13 (main, caller of example.com/shop/shop.(*Cart).Sum)",
+				"shop/cart.go:25-30 (sumAll, caller of example.com/shop/shop.(*Cart).Sum)",
+			},
+		},
+		{
+			// 型の場合は参照している宣言
+			spec: "shop.Item",
+			want: []string{
+				"shop/cart.go:3-6 (definition of shop.Item)",
+				"shop/cart.go:9-12 (Cart, refers to shop.Item)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := FindSymbols(shop, []string{tt.spec})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range s.Refs {
+				got = append(got, r.Location()+" ("+r.Note()+")")
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("refs:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestFindSymbolsErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"shop", "want pkg.Name or pkg.Type.Method"},
+		{"store.Cart", `no local package "store"`},
+		{"shop.Basket", "not found in example.com/shop/shop"},
+		{"shop.Cart.Empty", "not found in example.com/shop/shop"},
+	}
+	for _, tt := range tests {
+		if _, err := FindSymbols(shop, []string{tt.spec}); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("FindSymbols(%s) = %v, want an error containing %q", tt.spec, err, tt.want)
+		}
+	}
+}
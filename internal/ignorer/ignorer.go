@@ -2,6 +2,8 @@ package ignorer
 
 import (
 	"embed" // 追加
+	"io"
+	"log/slog"
 	"os"
 )
 
@@ -11,11 +13,20 @@ var defaultIgnoreFS embed.FS
 
 type Ignorer struct {
 	matchers []Matcher
+	log      *slog.Logger
 }
 
 func NewIgnorer(matchers ...Matcher) *Ignorer {
 	return &Ignorer{
 		matchers: matchers,
+		log:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+}
+
+// SetLogger はルールの読み込み状況の記録先を設定します。
+func (i *Ignorer) SetLogger(l *slog.Logger) {
+	if l != nil {
+		i.log = l
 	}
 }
 
@@ -28,7 +39,7 @@ func (i *Ignorer) LoadDefaults() error {
 	defer f.Close()
 
 	// GitIgnoreMatcherを再利用してルールを追加
-	matcher := NewGitIgnoreMatcher(f)
+	matcher := Named("default", NewGitIgnoreMatcher(f))
 	// デフォルトルールは「最優先」ではなく「ベース」なので、リストの先頭に追加したいが、
 	// 構造上は NewIgnorer 直後に呼べば先頭になるため、単純に AddMatcher でOK。
 	i.AddMatcher(matcher)
@@ -36,16 +47,31 @@ func (i *Ignorer) LoadDefaults() error {
 }
 
 func (i *Ignorer) AddMatcher(m Matcher) {
+	source := "custom"
+	if s, ok := m.(interface{ Source() string }); ok {
+		source = s.Source()
+	}
+	i.log.Debug("ignore rules added", "source", source)
 	i.matchers = append(i.matchers, m)
 }
 
 func (i *Ignorer) ShouldIgnore(path string, isDir bool) bool {
+	ignored, _ := i.Explain(path, isDir)
+	return ignored
+}
+
+// Explain は除外判定に加えて、除外の根拠となったルールの出所（ファイル名など）を返します。
+// 出所を持たない Matcher の場合は "custom" を返します。
+func (i *Ignorer) Explain(path string, isDir bool) (bool, string) {
 	for _, m := range i.matchers {
 		if m.Match(path, isDir) {
-			return true
+			if s, ok := m.(interface{ Source() string }); ok {
+				return true, s.Source()
+			}
+			return true, "custom"
 		}
 	}
-	return false
+	return false, ""
 }
 
 func (i *Ignorer) LoadIgnoreFile(path string) error {
@@ -58,7 +84,7 @@ func (i *Ignorer) LoadIgnoreFile(path string) error {
 	}
 	defer f.Close()
 
-	matcher := NewGitIgnoreMatcher(f)
+	matcher := Named(path, NewGitIgnoreMatcher(f))
 	i.AddMatcher(matcher)
 	return nil
 }
@@ -14,6 +14,23 @@ type Matcher interface {
 	Match(path string, isDir bool) bool
 }
 
+// namedMatcher は Matcher にルールの出所（ファイル名など）を付与します。
+// レポートで「どのルールにより除外されたか」を示すために使用します。
+type namedMatcher struct {
+	Matcher
+	source string
+}
+
+// Named は source を出所として報告する Matcher を返します。
+func Named(source string, m Matcher) Matcher {
+	return &namedMatcher{Matcher: m, source: source}
+}
+
+// Source はルールの出所を返します。
+func (n *namedMatcher) Source() string {
+	return n.source
+}
+
 // ignoreRule は1行分のルールを表します。
 type ignoreRule struct {
 	pattern string
@@ -0,0 +1,44 @@
+package language
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// binaryExtensions は内容を確認せずにバイナリとみなす拡張子です。
+// 画像・音声・動画・アーカイブ・実行ファイル・フォントなど、テキストとして読めないことが明らかな形式に限ります。
+var binaryExtensions = map[string]bool{
+	// 画像
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".ico": true,
+	".webp": true, ".tif": true, ".tiff": true, ".psd": true, ".heic": true, ".avif": true,
+	// 音声・動画
+	".mp3": true, ".wav": true, ".flac": true, ".ogg": true, ".m4a": true, ".aac": true,
+	".mp4": true, ".m4v": true, ".mov": true, ".avi": true, ".mkv": true, ".webm": true,
+	// アーカイブ
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true,
+	".7z": true, ".rar": true, ".jar": true, ".war": true, ".whl": true,
+	// 実行ファイル・オブジェクト
+	".exe": true, ".dll": true, ".so": true, ".dylib": true, ".o": true, ".a": true,
+	".lib": true, ".obj": true, ".class": true, ".pyc": true, ".pyo": true, ".wasm": true,
+	// フォント
+	".ttf": true, ".otf": true, ".woff": true, ".woff2": true, ".eot": true,
+	// 文書・データベース
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true,
+	".pptx": true, ".sqlite": true, ".sqlite3": true, ".db": true,
+}
+
+// IsTextExtension は拡張子が対応表に登録されている（プログラミング言語・テキスト形式である）かを返します。
+func (m *Mapper) IsTextExtension(path string) bool {
+	_, ok := m.extMap[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// IsBinaryExtension は拡張子が既知のバイナリ形式かを返します。
+// 対応表（カスタムマップを含む）に登録されている拡張子はテキストとして扱います。
+func (m *Mapper) IsBinaryExtension(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	if _, ok := m.extMap[ext]; ok {
+		return false
+	}
+	return binaryExtensions[ext]
+}
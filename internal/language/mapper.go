@@ -16,6 +16,19 @@ type Mapper struct {
 	// 仕様変更: LinguistMap形式 (map[string][]string) に対応
 	// Key: 拡張子 (例: ".go"), Value: [言語名, 親言語...]
 	extMap map[string][]string
+	// override はパスごとの言語の指定です（nil の場合は拡張子のみで判定します）。
+	override Override
+}
+
+// Override はパスごとの言語の指定（.gitattributes の linguist-language など）です。
+// ok が false の場合は拡張子で判定します。lang が空の場合は言語を判定しません。
+type Override func(path string) (lang string, ok bool)
+
+// WithOverride は拡張子の対応表を共有し、o による指定を優先する Mapper を返します。
+func (m *Mapper) WithOverride(o Override) *Mapper {
+	c := *m
+	c.override = o
+	return &c
 }
 
 // NewMapper はデフォルト設定とオプションのカスタム設定ファイルをロードしてMapperを作成します。
@@ -45,21 +58,44 @@ func NewMapper(customMapPath string) (*Mapper, error) {
 			return nil, err
 		}
 
-		// 上書きマージ
-		for k, v := range customMap {
-			m.extMap[k] = v
-		}
+		m.Merge(customMap)
 	}
 
 	return m, nil
 }
 
+// Merge は拡張子ベースで対応表を上書きマージします。
+func (m *Mapper) Merge(custom map[string][]string) {
+	for k, v := range custom {
+		m.extMap[k] = v
+	}
+}
+
 // GetLanguage はファイルパス（拡張子）から言語名を返します。
 // LinguistMap形式の配列の最初の要素を言語名として返します。
 func (m *Mapper) GetLanguage(path string) string {
+	if m.override != nil {
+		if lang, ok := m.override(path); ok {
+			return lang
+		}
+	}
 	ext := strings.ToLower(filepath.Ext(path))
 	if langs, ok := m.extMap[ext]; ok && len(langs) > 0 {
 		return langs[0]
 	}
 	return ""
 }
+
+// Languages はファイルパス（拡張子）に対応する言語名の候補を全て返します。
+// 1つの拡張子が複数の言語で使われる場合（.h など）、GetLanguage は最初の候補のみを返します。
+func (m *Mapper) Languages(path string) []string {
+	if m.override != nil {
+		if lang, ok := m.override(path); ok {
+			if lang == "" {
+				return nil
+			}
+			return []string{lang}
+		}
+	}
+	return m.extMap[strings.ToLower(filepath.Ext(path))]
+}
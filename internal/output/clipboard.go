@@ -4,21 +4,29 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"os/exec"
 	"runtime"
+	"strings"
 )
 
 // ClipboardStrategy はOSのコマンドを利用してクリップボードへ出力する戦略です。
 type ClipboardStrategy struct {
 	ctx    context.Context // キャンセル制御用
+	log    *slog.Logger
 	buffer *bytes.Buffer
 }
 
 // NewClipboardStrategy はContextを受け取るように修正されました。
 // これにより、プロセス実行時のキャンセル制御が可能になります。
-func NewClipboardStrategy(ctx context.Context) *ClipboardStrategy {
+func NewClipboardStrategy(ctx context.Context, logger *slog.Logger) *ClipboardStrategy {
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
 	return &ClipboardStrategy{
 		ctx:    ctx,
+		log:    logger,
 		buffer: new(bytes.Buffer),
 	}
 }
@@ -50,6 +58,17 @@ func (s *ClipboardStrategy) Close() error {
 		return fmt.Errorf("unsupported platform for clipboard: %s", runtime.GOOS)
 	}
 
+	s.log.Debug("copying to clipboard", "command", cmd.Path, "bytes", s.buffer.Len())
+
+	// コマンドの失敗理由（xclip の DISPLAY 未設定など）をエラーに含める
+	var stderr bytes.Buffer
 	cmd.Stdin = s.buffer
-	return cmd.Run()
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %w: %s", cmd.Path, err, msg)
+		}
+		return fmt.Errorf("%s: %w", cmd.Path, err)
+	}
+	return nil
 }
@@ -1,6 +1,7 @@
 package output
 
 import (
+	"errors"
 	"io"
 )
 
@@ -28,13 +29,12 @@ func (m *MultiStrategy) Write(p []byte) (n int, err error) {
 	return len(p), nil
 }
 
-// Close は全てのStrategyを閉じます。エラーは最初の一つを返します。
+// Close は全てのStrategyを閉じます。
+// 一部の出力先の失敗が埋もれないよう、発生した全てのエラーを結合して返します。
 func (m *MultiStrategy) Close() error {
-	var firstErr error
+	var errs []error
 	for _, s := range m.strategies {
-		if err := s.Close(); err != nil && firstErr == nil {
-			firstErr = err
-		}
+		errs = append(errs, s.Close())
 	}
-	return firstErr
+	return errors.Join(errs...)
 }
@@ -0,0 +1,199 @@
+package processor
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"image"
+	_ "image/gif" // image.DecodeConfig で使用
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// maxListedEntries はアーカイブの内容として列挙するエントリ数の上限です（総数は全て数えます）。
+const maxListedEntries = 100
+
+// BinaryInfo はバイナリファイルの内容の代わりに出力するメタデータです。
+type BinaryInfo struct {
+	Format string // "png" や "zip" などの形式名（不明な場合は空）
+	Width  int    // 画像の幅（画像以外は 0）
+	Height int    // 画像の高さ（画像以外は 0）
+
+	Entries           []string // アーカイブのエントリ（最大 maxListedEntries 件）
+	EntryCount        int      // アーカイブのエントリ総数
+	ListingIncomplete bool     // 途中までしか列挙できなかった場合 true
+
+	SHA256  string // 内容全体の SHA-256（16進数）
+	HexDump string // 先頭 HexDumpBytes バイトの hex.Dump 形式（無効な場合は空）
+}
+
+// errListingIncomplete はストリームのままではアーカイブの列挙を続けられないことを示します。
+var errListingIncomplete = errors.New("listing incomplete")
+
+// inspectBinary はバイナリファイルを1回だけ読み通し、形式・画像サイズ・アーカイブの内容・SHA-256 を求めます。
+// 形式ごとの解析に失敗しても、その項目を空にするだけでエラーにはしません（読み込みエラーのみ返します）。
+func (p *Processor) inspectBinary(ctx context.Context, src *peekSource, sample []byte) (*BinaryInfo, error) {
+	info := &BinaryInfo{Format: sniffFormat(sample)}
+
+	if n := p.opts.HexDumpBytes; n > 0 {
+		head, err := src.peek(n)
+		if err != nil {
+			return nil, err
+		}
+		info.HexDump = hex.Dump(head)
+	}
+
+	// 解析用の読み込みも含め、全てのバイトがちょうど1回ハッシュに渡るようにする
+	h := sha256.New()
+	r := io.TeeReader(src.reader(), h)
+
+	switch info.Format {
+	case "png", "jpeg", "gif":
+		if cfg, _, err := image.DecodeConfig(r); err == nil {
+			info.Width, info.Height = cfg.Width, cfg.Height
+		}
+	case "webp":
+		info.Width, info.Height = webpSize(sample)
+	case "zip":
+		info.ListingIncomplete = listZip(r, info.addEntry) != nil
+	case "tar":
+		info.ListingIncomplete = listTar(r, info.addEntry) != nil
+	case "gzip":
+		// tar.gz の場合は展開しながら列挙する
+		if zr, err := gzip.NewReader(r); err == nil {
+			br := bufio.NewReaderSize(zr, 512)
+			if head, _ := br.Peek(262); isTar(head) {
+				info.Format = "tar.gz"
+				info.ListingIncomplete = listTar(br, info.addEntry) != nil
+			}
+		}
+	}
+
+	// 解析で読み残した分を読み捨ててハッシュを完成させる
+	if err := p.copyCancellable(ctx, io.Discard, r); err != nil {
+		return nil, err
+	}
+	info.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return info, nil
+}
+
+// addEntry はアーカイブのエントリを記録します。
+func (b *BinaryInfo) addEntry(name string) {
+	b.EntryCount++
+	if len(b.Entries) < maxListedEntries {
+		b.Entries = append(b.Entries, name)
+	}
+}
+
+// sniffFormat は先頭のバイト列から形式名を返します。
+func sniffFormat(sample []byte) string {
+	if isTar(sample) {
+		return "tar"
+	}
+	for _, m := range magicNumbers {
+		if !bytes.HasPrefix(sample, m.sig) {
+			continue
+		}
+		if m.name == "riff" && len(sample) >= 12 {
+			switch string(sample[8:12]) {
+			case "WEBP":
+				return "webp"
+			case "WAVE":
+				return "wav"
+			case "AVI ":
+				return "avi"
+			}
+		}
+		return m.name
+	}
+	return ""
+}
+
+// isTar は POSIX tar のヘッダー（オフセット 257 の "ustar"）を持つかを返します。
+func isTar(b []byte) bool {
+	return len(b) >= 262 && string(b[257:262]) == "ustar"
+}
+
+// webpSize は WebP のヘッダーから画像サイズを返します（不明な場合は 0, 0）。
+func webpSize(b []byte) (int, int) {
+	if len(b) < 30 {
+		return 0, 0
+	}
+	switch string(b[12:16]) {
+	case "VP8X": // 拡張形式: 24bit の (幅-1), (高さ-1)
+		w := int(b[24]) | int(b[25])<<8 | int(b[26])<<16
+		h := int(b[27]) | int(b[28])<<8 | int(b[29])<<16
+		return w + 1, h + 1
+	case "VP8L": // ロスレス: 14bit の (幅-1), (高さ-1)
+		if b[20] != 0x2F {
+			return 0, 0
+		}
+		bits := binary.LittleEndian.Uint32(b[21:25])
+		return int(bits&0x3FFF) + 1, int(bits>>14&0x3FFF) + 1
+	case "VP8 ": // ロッシー: キーフレームの開始コードの後に 14bit の幅・高さ
+		if !bytes.Equal(b[23:26], []byte{0x9D, 0x01, 0x2A}) {
+			return 0, 0
+		}
+		return int(binary.LittleEndian.Uint16(b[26:28]) & 0x3FFF), int(binary.LittleEndian.Uint16(b[28:30]) & 0x3FFF)
+	}
+	return 0, 0
+}
+
+// listZip は zip のローカルファイルヘッダーを先頭から順に読み、エントリ名を add に渡します。
+// 末尾の中央ディレクトリを使わずストリームのまま列挙するため、サイズがデータ記述子にしか
+// 記録されていないエントリ（ストリーミングで作成された zip）に達した場合は errListingIncomplete を返します。
+func listZip(r io.Reader, add func(string)) error {
+	br := bufio.NewReader(r)
+	var hdr [30]byte
+	for {
+		if _, err := io.ReadFull(br, hdr[:4]); err != nil {
+			return err
+		}
+		if string(hdr[:4]) != "PK\x03\x04" {
+			return nil // 中央ディレクトリに到達
+		}
+		if _, err := io.ReadFull(br, hdr[4:]); err != nil {
+			return err
+		}
+		flags := binary.LittleEndian.Uint16(hdr[6:8])
+		size := binary.LittleEndian.Uint32(hdr[18:22])
+		nameLen := binary.LittleEndian.Uint16(hdr[26:28])
+		extraLen := binary.LittleEndian.Uint16(hdr[28:30])
+
+		name := make([]byte, nameLen)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return err
+		}
+		add(string(name))
+
+		if (flags&0x08 != 0 && size == 0) || size == 0xFFFFFFFF {
+			// データ記述子・zip64 ではここからデータの長さが分からない
+			return errListingIncomplete
+		}
+		if _, err := io.CopyN(io.Discard, br, int64(extraLen)+int64(size)); err != nil {
+			return err
+		}
+	}
+}
+
+// listTar は tar のエントリ名を add に渡します。
+func listTar(r io.Reader, add func(string)) error {
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		add(hdr.Name)
+	}
+}
@@ -0,0 +1,119 @@
+package processor
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestExecuteBinaryInfo(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 3, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	// サイズがローカルファイルヘッダーにある zip（全て列挙できる）
+	var zipData bytes.Buffer
+	zw := zip.NewWriter(&zipData)
+	for _, name := range []string{"a.txt", "dir/b.txt"} {
+		body := []byte("content of " + name)
+		w, err := zw.CreateRaw(&zip.FileHeader{
+			Name:               name,
+			Method:             zip.Store,
+			CRC32:              crc32.ChecksumIEEE(body),
+			CompressedSize64:   uint64(len(body)),
+			UncompressedSize64: uint64(len(body)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(body)
+	}
+	zw.Close()
+
+	// ストリーミングで作成した zip（データ記述子のため先頭のエントリ以降は列挙できない）
+	var streamed bytes.Buffer
+	zw = zip.NewWriter(&streamed)
+	for _, name := range []string{"x.txt", "y.txt"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(name))
+	}
+	zw.Close()
+
+	var tgz bytes.Buffer
+	gw := gzip.NewWriter(&tgz)
+	tw := tar.NewWriter(gw)
+	for _, name := range []string{"pkg/", "pkg/main.go"} {
+		hdr := &tar.Header{Name: name, Mode: 0o644, Typeflag: tar.TypeReg}
+		if strings.HasSuffix(name, "/") {
+			hdr.Typeflag = tar.TypeDir
+		}
+		tw.WriteHeader(hdr)
+	}
+	tw.Close()
+	gw.Close()
+
+	fsys := fstest.MapFS{
+		"logo.png":    {Data: pngData.Bytes()},
+		"bundle.zip":  {Data: zipData.Bytes()},
+		"stream.zip":  {Data: streamed.Bytes()},
+		"src.tar.gz":  {Data: tgz.Bytes()},
+		"unknown.bin": {Data: []byte("abc\x00\x01\x02def")},
+	}
+	out, _ := execute(t, fsys, Options{HexDumpBytes: 4})
+
+	sum := func(name string) string {
+		h := sha256.Sum256(fsys[name].Data)
+		return hex.EncodeToString(h[:])
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"logo.png", "- Format: png (3x2)\n"},
+		{"bundle.zip", "- Format: zip\n"},
+		{"bundle.zip", "- Entries (2):\n  - a.txt\n  - dir/b.txt\n"},
+		{"stream.zip", "- Entries (1+):\n  - x.txt\n"},
+		{"src.tar.gz", "- Format: tar.gz\n"},
+		{"src.tar.gz", "- Entries (2):\n  - pkg/\n  - pkg/main.go\n"},
+		{"unknown.bin", "- Size: 9 bytes\n- SHA-256: " + sum("unknown.bin") + "\n"},
+		{"unknown.bin", "```text\n" + hex.Dump([]byte("abc\x00")) + "```\n"},
+	}
+	for _, tt := range tests {
+		section := fileSection(out, tt.name)
+		if !strings.Contains(section, "(Binary file skipped)\n") {
+			t.Errorf("%s was not treated as binary:\n%s", tt.name, section)
+		}
+		if !strings.Contains(section, tt.want) {
+			t.Errorf("%s: section does not contain %q:\n%s", tt.name, tt.want, section)
+		}
+		if want := "- SHA-256: " + sum(tt.name) + "\n"; !strings.Contains(section, want) {
+			t.Errorf("%s: section does not contain %q:\n%s", tt.name, want, section)
+		}
+	}
+	if strings.Contains(fileSection(out, "unknown.bin"), "- Format:") {
+		t.Error("unknown.bin has a format line")
+	}
+}
+
+// fileSection は出力から name のセクション（次の "## File:" の手前まで）を取り出します。
+func fileSection(out, name string) string {
+	_, rest, ok := strings.Cut(out, "## File: "+name+"\n")
+	if !ok {
+		return ""
+	}
+	section, _, _ := strings.Cut(rest, "\n## File: ")
+	return section
+}
@@ -0,0 +1,137 @@
+package processor
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+// 分類の根拠（--verbose のログに出力します）。
+const (
+	byPattern   = "pattern"   // --text / --binary の指定
+	byAttribute = "attribute" // .gitattributes の binary / diff / text
+	byUnicode   = "unicode"   // BOM または UTF-16 の判定
+	byMagic     = "magic"     // 既知のファイル形式のシグネチャ
+	byExtension = "extension" // 既知のバイナリ拡張子
+	byNUL       = "nul"       // NUL バイトを含む
+	byControl   = "control"   // 制御文字の割合
+	byText      = "text"      // いずれにも該当しない（テキスト）
+)
+
+// magicNumbers はバイナリ形式のシグネチャです（先頭からの一致）。
+var magicNumbers = []struct {
+	name string
+	sig  []byte
+}{
+	{"png", []byte("\x89PNG\r\n\x1a\n")},
+	{"jpeg", []byte{0xFF, 0xD8, 0xFF}},
+	{"gif", []byte("GIF8")},
+	{"pdf", []byte("%PDF-")},
+	{"zip", []byte("PK\x03\x04")},
+	{"gzip", []byte{0x1F, 0x8B}},
+	{"bzip2", []byte("BZh")},
+	{"xz", []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}},
+	{"zstd", []byte{0x28, 0xB5, 0x2F, 0xFD}},
+	{"7z", []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}},
+	{"elf", []byte("\x7fELF")},
+	{"mach-o", []byte{0xCF, 0xFA, 0xED, 0xFE}},
+	{"mach-o", []byte{0xCE, 0xFA, 0xED, 0xFE}},
+	{"java-class", []byte{0xCA, 0xFE, 0xBA, 0xBE}},
+	{"wasm", []byte("\x00asm")},
+	{"sqlite", []byte("SQLite format 3\x00")},
+	{"riff", []byte("RIFF")},
+	{"ogg", []byte("OggS")},
+	{"mp3", []byte("ID3")},
+	{"woff", []byte("wOFF")},
+	{"woff2", []byte("wOF2")},
+	{"ico", []byte{0x00, 0x00, 0x01, 0x00}},
+}
+
+// maxControlRatio は制御文字の割合の上限です。これを超える場合はバイナリとみなします。
+// UTF-8 として正しい場合や拡張子が対応表に登録されている場合は、テキストである可能性が高いため上限を緩めます。
+const (
+	maxControlRatio     = 0.10
+	maxControlRatioText = 0.30
+)
+
+// classify はファイルがバイナリかどうかを判定し、判定の根拠を返します。
+// 判定は次の順に行い、最初に決まったものを採用します。
+//  1. --text / --binary の指定、.gitattributes の binary・-diff（バイナリ）と diff（テキスト）
+//  2. BOM・UTF-16（NUL バイトを含むテキスト）
+//  3. 既知のファイル形式のシグネチャ
+//  4. 既知のバイナリ拡張子
+//  5. NUL バイトの有無
+//  6. 制御文字の割合（Shift_JIS 等のレガシーな文字コードを想定し、0x80 以上は印字可能とみなす）。
+//     UTF-8 として正しい場合や拡張子が対応表にある場合は上限を緩める
+func (p *Processor) classify(name string, sample []byte) (binary bool, by string) {
+	if matchAny(p.opts.TextPatterns, name) {
+		return false, byPattern
+	}
+	if matchAny(p.opts.BinaryPatterns, name) {
+		return true, byPattern
+	}
+	if binary, ok := p.opts.Attributes.Binary(name); ok {
+		return binary, byAttribute
+	}
+	if len(sample) == 0 {
+		return false, byText
+	}
+	if _, ok := detectUnicode(sample); ok {
+		return false, byUnicode
+	}
+	for _, m := range magicNumbers {
+		if bytes.HasPrefix(sample, m.sig) {
+			return true, byMagic + ":" + m.name
+		}
+	}
+	if p.opts.Mapper.IsBinaryExtension(name) {
+		return true, byExtension
+	}
+	if bytes.IndexByte(sample, 0) != -1 {
+		return true, byNUL
+	}
+	// 制御文字の割合は UTF-8 の場合も確認する（制御文字のみのデータも UTF-8 としては正しいため）
+	limit := maxControlRatio
+	if validUTF8Prefix(sample) || p.opts.Mapper.IsTextExtension(name) {
+		limit = maxControlRatioText
+	}
+	if controlRatio(sample) > limit {
+		return true, byControl
+	}
+	return false, byText
+}
+
+// controlRatio はテキストでは通常使用されない制御文字の割合を返します。
+func controlRatio(b []byte) float64 {
+	var n int
+	for _, c := range b {
+		switch {
+		case c == '\t', c == '\n', c == '\r', c == '\f', c == '\v', c == 0x1B:
+			// 空白類と ESC（ISO-2022-JP の切り替えに使用）
+		case c < 0x20, c == 0x7F:
+			n++
+		}
+	}
+	return float64(n) / float64(len(b))
+}
+
+// matchPattern は path.Match 形式の pattern を判定します。
+// pattern が '/' を含む場合はルートからの相対パス全体、含まない場合はファイル名と照合します。
+func matchPattern(pattern, name string) bool {
+	target := path.Base(name)
+	if strings.Contains(pattern, "/") {
+		target = name
+	}
+	ok, _ := path.Match(pattern, target)
+	return ok
+}
+
+// matchAny は patterns のいずれかが name に一致するかを返します。
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,403 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// encodingSampleSize は文字コードの判定に使用する先頭のバイト数です。
+// BOM なしの UTF-8 と判定した場合は、最初の不正なバイト列の位置から同じ長さで判定し直します（detectEncoding）。
+const encodingSampleSize = 8 * 1024
+
+// textEncoding は検出したテキストの文字コードです。
+type textEncoding struct {
+	name string // 元の文字コード名（BOM なしの UTF-8 の場合は空）
+	enc  encoding.Encoding
+	// late が true の場合、最初の不正なバイト列までは UTF-8 として正しく、それ以降が enc です。
+	late bool
+}
+
+var (
+	encUTF8    = textEncoding{enc: unicode.UTF8}
+	encUTF8BOM = textEncoding{name: "UTF-8 with BOM", enc: unicode.UTF8BOM}
+	encUTF16LE = textEncoding{name: "UTF-16LE", enc: unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)}
+	encUTF16BE = textEncoding{name: "UTF-16BE", enc: unicode.UTF16(unicode.BigEndian, unicode.UseBOM)}
+	encSJIS    = textEncoding{name: "Shift_JIS", enc: japanese.ShiftJIS}
+	encEUCJP   = textEncoding{name: "EUC-JP", enc: japanese.EUCJP}
+	encLatin1  = textEncoding{name: "ISO-8859-1", enc: charmap.ISO8859_1}
+)
+
+// reader は r を UTF-8 に変換する Reader を返します。
+// 不正なバイト列は U+FFFD に置き換えます。BOM は取り除きます。
+// BOM なしの UTF-8 は判定した範囲より後ろに不正なバイト列があり得るため、その位置から文字コードを推定し直します。
+func (e textEncoding) reader(r io.Reader) io.Reader {
+	switch {
+	case e == encUTF8:
+		return &utf8Reader{r: r}
+	case e.late:
+		return &utf8Reader{r: r, fallback: e.enc}
+	}
+	return transform.NewReader(r, e.enc.NewDecoder())
+}
+
+// decode はプレビュー用に b を UTF-8 に変換します。
+func (e textEncoding) decode(b []byte) string {
+	out, err := io.ReadAll(e.reader(bytes.NewReader(b)))
+	if err != nil {
+		return string(b)
+	}
+	return string(out)
+}
+
+// utf8Reader は UTF-8 として正しい間はそのまま読み、最初の不正なバイト列以降を
+// fallback（nil の場合は detectLegacy で推定した文字コード）で変換します。
+// 先頭のサンプルが ASCII のみで、後半に Shift_JIS などが現れるファイルのためのものです。
+type utf8Reader struct {
+	r        io.Reader
+	fallback encoding.Encoding
+	buf      []byte // 読み込み済みで返していないバイト列
+	valid    int    // buf の先頭の、UTF-8 として正しいことを確認したバイト数
+	eof      bool
+	legacy   io.Reader // 不正なバイト列以降の Reader（切り替え前は nil）
+}
+
+func (u *utf8Reader) Read(p []byte) (int, error) {
+	for {
+		if u.legacy != nil {
+			return u.legacy.Read(p)
+		}
+		if u.valid > 0 {
+			n := copy(p, u.buf[:u.valid])
+			u.buf, u.valid = u.buf[n:], u.valid-n
+			return n, nil
+		}
+
+		valid, invalid := utf8ValidPrefix(u.buf, u.eof)
+		switch {
+		case valid > 0:
+			u.valid = valid
+			continue
+		case invalid:
+			if err := u.switchLegacy(); err != nil {
+				return 0, err
+			}
+			continue
+		case u.eof:
+			return 0, io.EOF
+		}
+
+		chunk := make([]byte, 4096)
+		n, err := u.r.Read(chunk)
+		u.buf = append(u.buf, chunk[:n]...)
+		if err == io.EOF {
+			u.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// switchLegacy は buf の先頭（不正なバイト列）以降を fallback で読むようにします。
+// fallback がない場合は、buf の先頭から encodingSampleSize バイトまでで文字コードを推定します。
+func (u *utf8Reader) switchLegacy() error {
+	enc := u.fallback
+	if enc == nil {
+		chunk := make([]byte, 4096)
+		for !u.eof && len(u.buf) < encodingSampleSize {
+			n, err := u.r.Read(chunk)
+			u.buf = append(u.buf, chunk[:n]...)
+			if err == io.EOF {
+				u.eof = true
+			} else if err != nil {
+				return err
+			}
+		}
+		enc = detectLegacy(u.buf).enc
+	}
+	u.legacy = transform.NewReader(io.MultiReader(bytes.NewReader(u.buf), u.r), enc.NewDecoder())
+	u.buf = nil
+	return nil
+}
+
+// detectEncoding は name の文字コードを、先頭の sample から判定します。
+// BOM なしの UTF-8 と判定した場合は、見出しに元の文字コードを記録できるよう、ファイルを開き直して
+// サンプルより後ろ（maxConvertSize まで）に不正なバイト列がないかを確認し、あればその位置から判定し直します。
+// maxConvertSize より後ろで初めて現れる場合は、出力時に utf8Reader が切り替えます（見出しには記録されません）。
+func (p *Processor) detectEncoding(name string, sample []byte, size int64) textEncoding {
+	if enc, ok := detectUnicode(sample); ok {
+		return enc
+	}
+	enc := detectLegacy(sample)
+	if enc != encUTF8 || size <= int64(len(sample)) {
+		return enc
+	}
+	f, err := p.fsys.Open(name)
+	if err != nil {
+		return enc
+	}
+	defer f.Close()
+	if late, ok := scanLateEncoding(io.LimitReader(f, maxConvertSize)); ok {
+		return late
+	}
+	return enc
+}
+
+// scanLateEncoding は r を UTF-8 として読み、最初の不正なバイト列があればその位置から推定した文字コードを返します。
+func scanLateEncoding(r io.Reader) (textEncoding, bool) {
+	var (
+		buf   []byte
+		chunk = make([]byte, 32*1024)
+		eof   bool
+	)
+	for {
+		n, invalid := utf8ValidPrefix(buf, eof)
+		buf = buf[n:]
+		if invalid {
+			for !eof && len(buf) < encodingSampleSize {
+				m, err := r.Read(chunk)
+				buf = append(buf, chunk[:m]...)
+				if err != nil {
+					eof = true
+				}
+			}
+			enc := detectLegacy(buf)
+			enc.late = true
+			return enc, true
+		}
+		if eof {
+			return textEncoding{}, false
+		}
+		m, err := r.Read(chunk)
+		buf = append(buf, chunk[:m]...)
+		if err == io.EOF {
+			eof = true
+		} else if err != nil {
+			return textEncoding{}, false
+		}
+	}
+}
+
+// utf8ValidPrefix は b の先頭の UTF-8 として正しい部分の長さと、その直後が不正なバイト列かを返します。
+// eof が false の場合、末尾で切れた文字は続きを読むまで判定しません。
+func utf8ValidPrefix(b []byte, eof bool) (n int, invalid bool) {
+	for n < len(b) {
+		if b[n] < utf8.RuneSelf {
+			n++
+			continue
+		}
+		if !eof && !utf8.FullRune(b[n:]) {
+			return n, false
+		}
+		r, size := utf8.DecodeRune(b[n:])
+		if r == utf8.RuneError && size == 1 {
+			return n, true
+		}
+		n += size
+	}
+	return n, false
+}
+
+// detectUnicode は BOM と NUL バイトの配置から UTF-8 (BOM付き) / UTF-16 を判定します。
+// UTF-16 は NUL バイトを含むためバイナリ判定より前に行う必要があります。
+func detectUnicode(sample []byte) (textEncoding, bool) {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return encUTF8BOM, true
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return encUTF16LE, true
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return encUTF16BE, true
+	}
+
+	// BOM なしの UTF-16: ASCII 主体のテキストでは上位バイトの NUL が偶数・奇数の一方に偏る
+	if len(sample) < 4 {
+		return textEncoding{}, false
+	}
+	var even, odd int
+	for i, c := range sample {
+		if c != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			even++
+		} else {
+			odd++
+		}
+	}
+	half := len(sample) / 2
+	switch {
+	case odd*10 >= half*3 && even == 0:
+		return encUTF16LE, true
+	case even*10 >= half*3 && odd == 0:
+		return encUTF16BE, true
+	}
+	return textEncoding{}, false
+}
+
+// detectLegacy はテキストと判定されたファイルの文字コードを推定します。
+// UTF-8 として正しければ UTF-8、次に Shift_JIS / EUC-JP、いずれでもなければ Latin-1 とみなします。
+// Latin-1 の欧文（"f\xfcr" や "Gr\xfc\xdfe" など）も Shift_JIS / EUC-JP として正しい場合があるため、
+// 仮名・漢字の領域に入る文字の数を根拠とし、根拠が弱い場合は Latin-1 とみなします。
+func detectLegacy(sample []byte) textEncoding {
+	if validUTF8Prefix(sample) {
+		return encUTF8
+	}
+
+	// ひらがな・カタカナを含む Shift_JIS は 0x81-0x9F の先頭バイトを含み EUC-JP としては不正になる。
+	// 両方として正しい場合は EUC-JP の可能性が高い
+	if validEUCJP(sample) && japaneseLikely(eucJPEvidence(sample)) {
+		return encEUCJP
+	}
+	if validShiftJIS(sample) && japaneseLikely(shiftJISEvidence(sample)) {
+		return encSJIS
+	}
+	return encLatin1
+}
+
+// japaneseLikely は日本語の文字らしいバイト列の数 good と、そうでないものの数 bad から、日本語のテキストかを判定します。
+func japaneseLikely(good, bad int) bool {
+	return good > 0 && good >= 2*bad
+}
+
+// shiftJISEvidence は b を Shift_JIS として読んだ場合の根拠を数えます。
+// JIS X 0208 の記号・仮名・漢字の行（と NEC 特殊文字）の2バイト文字を根拠とし、
+// 未定義・外字の行の文字、英字に挟まれた英字を2バイト目に持つ文字（Latin-1 の "\xe4nd" など）、
+// 英字に隣接する単独の半角カナ（Latin-1 の "\xc4nderung" など）を反証とします。
+func shiftJISEvidence(b []byte) (good, bad int) {
+	prevLetter := false // 直前の文字が ASCII の英字か（2バイト文字の2バイト目は含まない）
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c < 0x80:
+			prevLetter = isASCIILetter(b, i)
+			continue
+		case c >= 0xA1 && c <= 0xDF:
+			// 半角カナは通常連続して現れる
+			switch {
+			case isHalfwidthKana(b, i-1) || isHalfwidthKana(b, i+1):
+				good++
+			case prevLetter || isASCIILetter(b, i+1):
+				bad++
+			}
+		case i+1 < len(b):
+			row := c <= 0x84 || (c >= 0x87 && c <= 0x9F) || (c >= 0xE0 && c <= 0xEA)
+			if !row || (isASCIILetter(b, i+1) && (prevLetter || isASCIILetter(b, i+2))) {
+				bad++
+			} else {
+				good++
+			}
+			i++
+		}
+		prevLetter = false
+	}
+	return good, bad
+}
+
+// eucJPEvidence は b を EUC-JP として読んだ場合の根拠を数えます。
+// JIS X 0208 の記号・仮名・漢字の行（と NEC 特殊文字）の文字、半角カナ、補助漢字を根拠とし、
+// 未定義の行の文字（Latin-1 の "\xfc\xdf" など）を反証とします。
+func eucJPEvidence(b []byte) (good, bad int) {
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c < 0x80:
+		case c == 0x8E:
+			good++
+			i++
+		case c == 0x8F:
+			good++
+			i += 2
+		default:
+			if (c >= 0xA1 && c <= 0xA8) || c == 0xAD || (c >= 0xB0 && c <= 0xF4) {
+				good++
+			} else {
+				bad++
+			}
+			i++
+		}
+	}
+	return good, bad
+}
+
+// isHalfwidthKana は b[i] が Shift_JIS の半角カナかを返します（範囲外の場合は false）。
+func isHalfwidthKana(b []byte, i int) bool {
+	return i >= 0 && i < len(b) && b[i] >= 0xA1 && b[i] <= 0xDF
+}
+
+// isASCIILetter は b[i] が ASCII の英字かを返します（範囲外の場合は false）。
+func isASCIILetter(b []byte, i int) bool {
+	return i >= 0 && i < len(b) && (b[i]|0x20 >= 'a' && b[i]|0x20 <= 'z')
+}
+
+// validUTF8Prefix は末尾で切れた文字を除いて b が正しい UTF-8 かを返します。
+func validUTF8Prefix(b []byte) bool {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				b = b[:i]
+			}
+			break
+		}
+	}
+	return utf8.Valid(b)
+}
+
+// validShiftJIS は b が Shift_JIS として正しいかを返します（末尾で切れた文字は許容）。
+func validShiftJIS(b []byte) bool {
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c < 0x80, c >= 0xA1 && c <= 0xDF:
+			// ASCII・半角カナ
+		case c >= 0x81 && c <= 0x9F, c >= 0xE0 && c <= 0xFC:
+			if i+1 == len(b) {
+				return true
+			}
+			if t := b[i+1]; t < 0x40 || t == 0x7F || t > 0xFC {
+				return false
+			}
+			i++
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// validEUCJP は b が EUC-JP として正しいかを返します（末尾で切れた文字は許容）。
+func validEUCJP(b []byte) bool {
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		var n int
+		lo, hi := byte(0xA1), byte(0xFE)
+		switch {
+		case c < 0x80:
+			continue
+		case c == 0x8E: // 半角カナ
+			n, hi = 1, 0xDF
+		case c == 0x8F: // 補助漢字
+			n = 2
+		case c >= 0xA1 && c <= 0xFE:
+			n = 1
+		default:
+			return false
+		}
+		for j := 1; j <= n; j++ {
+			if i+j == len(b) {
+				return true
+			}
+			if t := b[i+j]; t < lo || t > hi {
+				return false
+			}
+		}
+		i += n
+	}
+	return true
+}
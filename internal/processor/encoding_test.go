@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"testing/iotest"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func sjis(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestUTF8ReaderFallback(t *testing.T) {
+	ascii := strings.Repeat("a", 9000) + "\n"
+	tests := []struct {
+		name string
+		src  []byte
+		want string
+	}{
+		{"ascii", []byte(ascii), ascii},
+		{"utf-8", []byte(ascii + "日本語\n"), ascii + "日本語\n"},
+		// 先頭のサンプルより後ろで現れる Shift_JIS
+		{"shift_jis after sample", append([]byte(ascii), sjis(t, "日本語のコメント\n")...), ascii + "日本語のコメント\n"},
+		// UTF-8 として正しい部分は、不正なバイト列の前までそのまま読む
+		{"utf-8 then shift_jis", append([]byte(ascii+"é\n"), sjis(t, "テスト\n")...), ascii + "é\nテスト\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 1バイトずつ読む場合も、文字の途中で切れずに判定する
+			for _, r := range []io.Reader{bytes.NewReader(tt.src), iotest.OneByteReader(bytes.NewReader(tt.src))} {
+				got, err := io.ReadAll(encUTF8.reader(r))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.want {
+					t.Errorf("got %q, want %q", got[len(got)-30:], tt.want[len(tt.want)-30:])
+				}
+			}
+		})
+	}
+}
+
+func TestExecuteLateShiftJIS(t *testing.T) {
+	// 最初の不正なバイト列が先頭のサンプルより後ろにある
+	ascii := strings.Repeat("// ascii only\n", 700)
+	if len(ascii) <= encodingSampleSize {
+		t.Fatalf("ASCII prefix of %d bytes fits in the sample", len(ascii))
+	}
+	data := append([]byte(ascii), sjis(t, "// 日本語のコメント\n")...)
+	fsys := fstest.MapFS{"legacy.go": {Data: data}}
+
+	out, _ := execute(t, fsys, Options{})
+	if !strings.Contains(out, "// 日本語のコメント\n") || strings.ContainsRune(out, '�') {
+		t.Errorf("Shift_JIS after the first %d bytes was not converted:\n%s", encodingSampleSize, out[len(out)-80:])
+	}
+	if !strings.Contains(out, "## File: legacy.go\n\n(Converted from Shift_JIS)\n") {
+		t.Errorf("header does not record the original encoding:\n%s", out[:80])
+	}
+
+	// 範囲の指定でも同様に判定する
+	out, _ = execute(t, fsys, Options{Snippets: []Snippet{{Path: "legacy.go", Start: 701}}, SnippetsOnly: true})
+	if !strings.Contains(out, "(Converted from Shift_JIS)") || !strings.Contains(out, "701 | // 日本語のコメント\n") {
+		t.Errorf("snippet was not converted:\n%s", out)
+	}
+}
+
+func eucjp(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := japanese.EUCJP.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDetectLegacy(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample []byte
+		want   textEncoding
+	}{
+		{"utf-8", []byte("# 設定ファイル\n"), encUTF8},
+		{"shift_jis", sjis(t, "// 設定ファイルを読み込みます。\nfunc load() {}\n"), encSJIS},
+		{"shift_jis katakana", sjis(t, "msg = \"テストデータ\"\n"), encSJIS},
+		{"shift_jis halfwidth kana", sjis(t, "label = \"ｶﾀｶﾅのテスト\"\n"), encSJIS},
+		{"euc-jp", eucjp(t, "# 設定ファイルを読み込みます。\n"), encEUCJP},
+		// 正しい Shift_JIS / EUC-JP でもあるが、仮名・漢字としての根拠が弱い Latin-1 の欧文
+		{"latin-1 german", []byte("Hinweise f\xfcr die \xc4nderung\n"), encLatin1},
+		{"latin-1 sharp s", []byte("Viele Gr\xfc\xdfe\n"), encLatin1},
+		{"latin-1 umlaut", []byte("Die Erkl\xe4rung der \xe4nderung\n"), encLatin1},
+		{"latin-1 french", []byte("d\xe9j\xe0 vu, caf\xe9\n"), encLatin1},
+	}
+	for _, tt := range tests {
+		if got := detectLegacy(tt.sample); got != tt.want {
+			t.Errorf("%s: detectLegacy(%q) = %q, want %q", tt.name, tt.sample, got.name, tt.want.name)
+		}
+	}
+}
+
+func TestExecuteLatin1(t *testing.T) {
+	out, _ := execute(t, fstest.MapFS{"notes.txt": {Data: []byte("Hinweise f\xfcr die \xc4nderung\nViele Gr\xfc\xdfe\n")}}, Options{})
+	if !strings.Contains(out, "(Converted from ISO-8859-1)") || !strings.Contains(out, "Hinweise für die Änderung\nViele Grüße\n") {
+		t.Errorf("Latin-1 text was not converted:\n%s", out)
+	}
+}
@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Entry は出力される1ファイル分の情報です。
+type Entry struct {
+	Path     string // fsys のルートからの相対パス（スラッシュ区切り）
+	Language string // language.Mapper による言語名（不明な場合は空）
+	Size     int64  // ファイルサイズ
+	Binary   bool   // バイナリとして内容をスキップした場合 true
+	// BinaryInfo はバイナリの場合のメタデータです（Binary が false の場合は nil）。
+	BinaryInfo *BinaryInfo
+	Truncated  bool   // 大容量ファイルとして先頭のみを出力する場合 true
+	Encoding   string // UTF-8 に変換した場合の元の文字コード（変換していない場合は空）
+	// Notebook は Jupyter ノートブックをセルの並び（Markdown）に変換した場合 true です。
+	// 内容はセルごとにフェンスを含むため、全体をフェンスで囲みません。
+	Notebook bool
+	// Outline は宣言のシグネチャのみに要約した（関数本体を省略した）場合 true です。
+	Outline bool
+	// StartLine / EndLine はファイルの一部（Snippet）を出力する場合の行の範囲です（ファイル全体の場合は 0）。
+	// EndLine が 0 の場合はファイルの末尾までです。
+	StartLine int
+	EndLine   int
+	// Note は見出しに添える説明です（Snippet.Note）。
+	Note string
+	// LineNumbers は内容の各行の先頭に元のファイルでの行番号を付けた場合 true です。
+	LineNumbers bool
+	// Generated は生成されたファイルとして内容を省略した場合の根拠（"protoc"、"linguist-generated" など）です。
+	// 空でない場合、Binary と同様に WriteHeader のみが呼ばれます。
+	Generated string
+}
+
+// Formatter は各エントリの書式を定義します。
+// 内容本体はストリーミングで Processor が書き込むため、Formatter は前後の装飾のみを担当します。
+type Formatter interface {
+	// WriteHeader は内容の前に呼ばれます。Binary の場合はこれのみが呼ばれます。
+	WriteHeader(w io.Writer, e Entry) error
+	// WriteFooter は内容の後に呼ばれます。
+	WriteFooter(w io.Writer, e Entry) error
+	// WriteDiff は差分を書き込みます。path が空の場合は全ファイルをまとめたパッチです。
+	WriteDiff(w io.Writer, path, ref, patch string) error
+}
+
+// LineNumberFormatter は行番号の書式を指定できる Formatter の拡張です。
+// Formatter がこのインターフェースも実装している場合、Processor は各行の先頭に FormatLineNumber の結果を付けます。
+// 実装していない場合は "  12 | " の形式（width 桁で右揃え）です。
+type LineNumberFormatter interface {
+	// FormatLineNumber は n 行目の先頭に付ける文字列を返します。width は右揃えに使う最小の桁数です。
+	FormatLineNumber(n, width int) string
+}
+
+// MarkdownFormatter は `## File:` 見出しとコードフェンスによる標準の書式です。
+type MarkdownFormatter struct{}
+
+// WriteHeader は見出しと開始フェンスを書き込みます。
+func (MarkdownFormatter) WriteHeader(w io.Writer, e Entry) error {
+	var err error
+	if e.Binary {
+		_, err = fmt.Fprintf(w, "\n## File: %s\n\n(Binary file skipped)\n", e.Path)
+		if err == nil && e.BinaryInfo != nil {
+			err = writeBinaryInfo(w, e)
+		}
+	} else if e.Generated != "" {
+		_, err = fmt.Fprintf(w, "\n## File: %s\n\n(Generated file skipped: %s)\n", e.Path, e.Generated)
+	} else if e.Notebook {
+		_, err = fmt.Fprintf(w, "\n## File: %s\n", e.Path)
+	} else {
+		_, err = fmt.Fprintf(w, "\n## File: %s%s\n%s\n```%s\n", e.Path, lineRangeLabel(e), entryNotes(e), e.Language)
+	}
+	return err
+}
+
+// lineRangeLabel はファイルの一部を出力する場合の範囲の表記（" (lines 120-180)"）を返します。
+func lineRangeLabel(e Entry) string {
+	switch {
+	case e.StartLine == 0:
+		return ""
+	case e.EndLine == 0:
+		return fmt.Sprintf(" (lines %d-)", e.StartLine)
+	case e.EndLine == e.StartLine:
+		return fmt.Sprintf(" (line %d)", e.StartLine)
+	}
+	return fmt.Sprintf(" (lines %d-%d)", e.StartLine, e.EndLine)
+}
+
+// entryNotes は内容を加工した場合の注記（文字コードの変換・アウトライン）を返します。
+func entryNotes(e Entry) string {
+	var b strings.Builder
+	if e.Note != "" {
+		fmt.Fprintf(&b, "\n(%s)\n", e.Note)
+	}
+	if e.Encoding != "" {
+		fmt.Fprintf(&b, "\n(Converted from %s)\n", e.Encoding)
+	}
+	if e.Outline {
+		b.WriteString("\n(Outline: function bodies omitted)\n")
+	}
+	return b.String()
+}
+
+// WriteFooter は終了フェンスを書き込みます。
+func (MarkdownFormatter) WriteFooter(w io.Writer, e Entry) error {
+	if e.Notebook {
+		return nil
+	}
+	_, err := io.WriteString(w, "\n```\n")
+	return err
+}
+
+// WriteDiff は見出しと ```diff フェンスで囲まれたパッチを書き込みます。
+// パッチは改行で終わることが保証されているため、フェンスの前に改行は追加しません。
+func (MarkdownFormatter) WriteDiff(w io.Writer, path, ref, patch string) error {
+	var heading string
+	if path == "" {
+		heading = fmt.Sprintf("\n## Diff (against %s)\n\n", ref)
+	} else {
+		heading = fmt.Sprintf("\n### Diff: %s (against %s)\n\n", path, ref)
+	}
+	_, err := io.WriteString(w, heading+"```diff\n"+patch+"```\n")
+	return err
+}
+
+// writeBinaryInfo はバイナリのメタデータを箇条書きで書き込みます。
+func writeBinaryInfo(w io.Writer, e Entry) error {
+	info := e.BinaryInfo
+	var b strings.Builder
+	b.WriteString("\n")
+	if info.Format != "" {
+		if info.Width > 0 && info.Height > 0 {
+			fmt.Fprintf(&b, "- Format: %s (%dx%d)\n", info.Format, info.Width, info.Height)
+		} else {
+			fmt.Fprintf(&b, "- Format: %s\n", info.Format)
+		}
+	}
+	fmt.Fprintf(&b, "- Size: %s bytes\n", formatCount(e.Size))
+	fmt.Fprintf(&b, "- SHA-256: %s\n", info.SHA256)
+
+	if info.EntryCount > 0 {
+		count := formatCount(int64(info.EntryCount))
+		if info.ListingIncomplete {
+			count += "+"
+		}
+		fmt.Fprintf(&b, "- Entries (%s):\n", count)
+		for _, name := range info.Entries {
+			fmt.Fprintf(&b, "  - %s\n", name)
+		}
+		if rest := info.EntryCount - len(info.Entries); rest > 0 {
+			fmt.Fprintf(&b, "  - ... (%s more)\n", formatCount(int64(rest)))
+		}
+	}
+
+	if info.HexDump != "" {
+		fmt.Fprintf(&b, "\n```text\n%s```\n", info.HexDump)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
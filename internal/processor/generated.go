@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// generatedHeaderLines は生成されたコードの目印を探す先頭の行数です。
+const generatedHeaderLines = 20
+
+// goGeneratedHeader は Go の標準の目印です（https://go.dev/s/generatedcode）。
+var goGeneratedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// generatedMarkers は代表的なコード生成ツールが先頭のコメントに書き込む文字列（小文字）と、その名前です。
+// ドキュメントの本文などでの言及と区別するため、コメントの行のみを対象とします。
+var generatedMarkers = []struct {
+	marker, tool string
+}{
+	{"generated by the protocol buffer compiler", "protoc"},
+	{"generated by mockgen", "mockgen"},
+	{"code generated by sqlc", "sqlc"},
+	{"openapi generator", "openapi"},
+	{"openapi-generator", "openapi"},
+	{"oapi-codegen", "openapi"},
+	{"swagger-codegen", "openapi"},
+	{"@generated", "@generated"},
+}
+
+// generated は name が生成された（または同梱された外部の）ファイルかを判定し、その根拠を返します。
+// .gitattributes の linguist-generated / linguist-vendored を優先し、なければ sample の先頭の行から目印を探します。
+// いずれかの属性が設定されていれば生成されたファイルとし、設定解除（-linguist-generated など）のみの場合は目印を探さずに対象外とします。
+func (p *Processor) generated(name string, sample []byte) (bool, string) {
+	attrs := p.opts.Attributes.Lookup(name)
+	unset := ""
+	for _, a := range []string{"linguist-generated", "linguist-vendored"} {
+		v, ok := attrs.Bool(a)
+		switch {
+		case ok && v:
+			return true, a
+		case ok:
+			unset = a
+		}
+	}
+	if unset != "" {
+		return false, unset
+	}
+
+	lines := bytes.SplitN(sample, []byte("\n"), generatedHeaderLines+1)
+	for i, line := range lines {
+		// 最後の要素は sample の途中で切れた行か、残り全体のため対象外
+		if i == generatedHeaderLines {
+			break
+		}
+		line = bytes.TrimRight(line, "\r")
+		if goGeneratedHeader.Match(line) {
+			return true, "Code generated ... DO NOT EDIT"
+		}
+		if !isCommentLine(line) {
+			continue
+		}
+		lower := bytes.ToLower(line)
+		for _, m := range generatedMarkers {
+			if bytes.Contains(lower, []byte(m.marker)) {
+				return true, m.tool
+			}
+		}
+		if bytes.Contains(lower, []byte("do not edit")) && bytes.Contains(lower, []byte("generated")) {
+			return true, "DO NOT EDIT"
+		}
+	}
+	return false, ""
+}
+
+// commentPrefixes は主な言語のコメントの開始記号です。
+var commentPrefixes = [][]byte{[]byte("//"), []byte("#"), []byte("/*"), []byte("*"), []byte("--"), []byte("<!--"), []byte(";"), []byte(`"""`)}
+
+// isCommentLine は line がコメントの行らしいかを返します。
+func isCommentLine(line []byte) bool {
+	line = bytes.TrimSpace(line)
+	for _, prefix := range commentPrefixes {
+		if bytes.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/kazuki-sk/codepack/internal/gitattr"
+)
+
+func TestGenerated(t *testing.T) {
+	attrs, err := gitattr.LoadAll(fstest.MapFS{gitattr.FileName: {Data: []byte(`vendor/** linguist-vendored
+vendor/patched/** -linguist-generated
+gen/** linguist-generated
+gen/kept.go linguist-generated=false
+`)}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Processor{opts: Options{Attributes: attrs}}
+
+	const marker = "// Code generated by protoc-gen-go. DO NOT EDIT.\n"
+	tests := []struct {
+		name   string
+		sample string
+		want   bool
+		by     string
+	}{
+		{"vendor/lib.go", "package lib\n", true, "linguist-vendored"},
+		// 一方が設定解除されていても、もう一方が設定されていれば対象
+		{"vendor/patched/lib.go", "package lib\n", true, "linguist-vendored"},
+		{"gen/api.go", "package api\n", true, "linguist-generated"},
+		// 設定解除のみの場合は目印があっても対象外
+		{"gen/kept.go", marker, false, "linguist-generated"},
+		{"main.go", marker, true, "Code generated ... DO NOT EDIT"},
+		{"main.go", "package main\n", false, ""},
+	}
+	for _, tt := range tests {
+		got, by := p.generated(tt.name, []byte(tt.sample))
+		if got != tt.want || (tt.by != "" && by != tt.by) {
+			t.Errorf("generated(%s) = (%v, %q), want (%v, %q)", tt.name, got, by, tt.want, tt.by)
+		}
+	}
+}
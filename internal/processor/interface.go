@@ -14,3 +14,32 @@ type LargeFileHandler interface {
 	// 戻り値: (true=含める/false=除外, エラー)
 	ShouldInclude(ctx context.Context, path string, size int64) (bool, error)
 }
+
+// LargeFileDecision は大容量ファイルの扱いを表します。
+type LargeFileDecision int
+
+const (
+	// LargeSkip はファイルを除外します。
+	LargeSkip LargeFileDecision = iota
+	// LargeInclude はファイル全体を含めます。
+	LargeInclude
+	// LargeTruncate は先頭の一部のみを含めます。
+	LargeTruncate
+)
+
+// LargeFileDecider は「含める/除外」以外の扱い（切り詰め）も返せる LargeFileHandler の拡張です。
+// LargeFileHandler がこのインターフェースも実装している場合、Processor は DecideLarge を優先して使用します。
+type LargeFileDecider interface {
+	DecideLarge(ctx context.Context, f LargeFile) (LargeFileDecision, error)
+}
+
+// DiffProvider は変更差分（パッチ）を提供するインターフェースです。
+// レビュー用途で、ファイル全文に加えて差分を出力するために使用します。
+type DiffProvider interface {
+	// Ref は比較元のリビジョンを返します。
+	Ref() string
+	// Patch は指定パス（対象ディレクトリからの相対、スラッシュ区切り）のパッチを返します。
+	Patch(path string) (string, bool)
+	// Paths は差分を持つ全ファイルのパスを返します。
+	Paths() []string
+}
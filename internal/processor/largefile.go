@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"bytes"
+	"io"
+)
+
+// maxPreviewBytes はプレビューのために先読みする最大バイト数です。
+// 1行が極端に長いファイル（minify済みJSなど）でもメモリ使用量を抑えます。
+const maxPreviewBytes = 64 * 1024
+
+// LargeFile は大容量ファイルの扱いを判断するための情報です。
+type LargeFile struct {
+	Path       string // fsys のルートからの相対パス（スラッシュ区切り）
+	Size       int64
+	Truncation Truncation // 切り詰めを選択した場合の設定
+
+	src *peekSource
+	enc textEncoding
+}
+
+// Head はファイルの先頭 lines 行を返します（最大 64KB）。
+// 先読みした内容は出力時にそのまま使用されるため、ファイルを再度開くことはありません。
+func (f LargeFile) Head(lines int) (string, error) {
+	if f.src == nil {
+		return "", nil
+	}
+	head, err := f.src.head(lines)
+	if err != nil || f.enc.enc == nil {
+		return head, err
+	}
+	return f.enc.decode([]byte(head)), nil
+}
+
+// peekSource は先読みしたバイト列と残りのストリームを保持します。
+type peekSource struct {
+	buf []byte
+	r   io.Reader
+	eof bool
+}
+
+// head は先頭 lines 行を返します。不足していれば maxPreviewBytes まで追加で読み込みます。
+func (s *peekSource) head(lines int) (string, error) {
+	chunk := make([]byte, 4096)
+	for !s.eof && bytes.Count(s.buf, []byte{'\n'}) < lines && len(s.buf) < maxPreviewBytes {
+		n, err := s.r.Read(chunk)
+		s.buf = append(s.buf, chunk[:n]...)
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			return "", err
+		}
+	}
+
+	end := 0
+	for i := 0; i < lines && end < len(s.buf); i++ {
+		next := bytes.IndexByte(s.buf[end:], '\n')
+		if next < 0 {
+			end = len(s.buf)
+			break
+		}
+		end += next + 1
+	}
+	return string(s.buf[:end]), nil
+}
+
+// peek は先頭 n バイトまでを先読みして返します（EOF の場合はそれ以下）。
+func (s *peekSource) peek(n int) ([]byte, error) {
+	chunk := make([]byte, 4096)
+	for !s.eof && len(s.buf) < n {
+		m, err := s.r.Read(chunk)
+		s.buf = append(s.buf, chunk[:m]...)
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if len(s.buf) > n {
+		return s.buf[:n], nil
+	}
+	return s.buf, nil
+}
+
+// reader は先読み分と残りのストリームを結合した Reader を返します。
+func (s *peekSource) reader() io.Reader {
+	return io.MultiReader(bytes.NewReader(s.buf), s.r)
+}
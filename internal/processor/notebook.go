@@ -0,0 +1,191 @@
+package processor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultNotebookOutputLines はノートブックのセルごとに残す出力の行数のデフォルト値です。
+const DefaultNotebookOutputLines = 20
+
+// notebook は nbformat 4 のうち、変換に使用する部分です。
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+	} `json:"metadata"`
+}
+
+type notebookCell struct {
+	CellType       string           `json:"cell_type"`
+	Source         multiline        `json:"source"`
+	ExecutionCount *int             `json:"execution_count"`
+	Outputs        []notebookOutput `json:"outputs"`
+}
+
+type notebookOutput struct {
+	OutputType string               `json:"output_type"`
+	Text       multiline            `json:"text"`
+	Data       map[string]multiline `json:"data"`
+	EName      string               `json:"ename"`
+	EValue     string               `json:"evalue"`
+}
+
+// multiline は nbformat で文字列または文字列の配列として保存されるテキストです。
+type multiline string
+
+func (m *multiline) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*m = multiline(s)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(b, &lines); err != nil {
+		// 画像などの JSON 値（application/json 等）は内容を使用しない
+		*m = ""
+		return nil
+	}
+	*m = multiline(strings.Join(lines, ""))
+	return nil
+}
+
+// isNotebook は name が Jupyter ノートブックかを返します。
+func isNotebook(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".ipynb")
+}
+
+// language はコードセルのフェンスに使用するカーネルの言語を返します。
+func (nb *notebook) language() string {
+	if l := nb.Metadata.Kernelspec.Language; l != "" {
+		return l
+	}
+	return nb.Metadata.LanguageInfo.Name
+}
+
+// renderNotebook はノートブックの JSON を、番号付きのセルを並べた Markdown に変換します。
+// outputLines はセルごとに残す出力の行数で、負の場合は出力を含めません。
+// 画像などテキスト以外の出力はプレースホルダーに置き換えます。
+func renderNotebook(data []byte, outputLines int) ([]byte, string, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, "", err
+	}
+	if nb.Cells == nil {
+		return nil, "", fmt.Errorf("not a notebook (nbformat 4): no cells")
+	}
+
+	lang := nb.language()
+	var b bytes.Buffer
+	for i, cell := range nb.Cells {
+		src := strings.TrimRight(string(cell.Source), "\n")
+		switch cell.CellType {
+		case "code":
+			if cell.ExecutionCount != nil {
+				fmt.Fprintf(&b, "\n### Cell %d (code, In [%d])\n\n", i+1, *cell.ExecutionCount)
+			} else {
+				fmt.Fprintf(&b, "\n### Cell %d (code)\n\n", i+1)
+			}
+			writeFenced(&b, lang, src)
+			if outputLines >= 0 {
+				if out := renderOutputs(cell.Outputs, outputLines); out != "" {
+					b.WriteString("\nOutput:\n\n")
+					writeFenced(&b, "text", out)
+				}
+			}
+		case "markdown":
+			fmt.Fprintf(&b, "\n### Cell %d (markdown)\n\n%s\n", i+1, src)
+		default: // raw
+			fmt.Fprintf(&b, "\n### Cell %d (%s)\n\n", i+1, cell.CellType)
+			writeFenced(&b, "", src)
+		}
+	}
+	return b.Bytes(), lang, nil
+}
+
+// renderOutputs はセルの出力をテキストにまとめ、limit 行を超える分を切り詰めます。
+func renderOutputs(outputs []notebookOutput, limit int) string {
+	var parts []string
+	for _, o := range outputs {
+		switch o.OutputType {
+		case "stream":
+			parts = append(parts, string(o.Text))
+		case "error":
+			parts = append(parts, o.EName+": "+o.EValue)
+		default: // execute_result, display_data
+			// 画像は常にプレースホルダーに置き換え、それ以外はテキスト表現があればそれを使用する
+			types := make([]string, 0, len(o.Data))
+			for t := range o.Data {
+				types = append(types, t)
+			}
+			sort.Strings(types)
+			text, hasText := o.Data["text/plain"]
+			for _, t := range types {
+				if strings.HasPrefix(t, "image/") || (!hasText && t != "text/plain") {
+					parts = append(parts, fmt.Sprintf("[%s output omitted]", t))
+				}
+			}
+			if hasText {
+				parts = append(parts, string(text))
+			}
+		}
+	}
+
+	out := strings.TrimRight(strings.Join(parts, "\n"), "\n")
+	if out == "" {
+		return ""
+	}
+	lines := strings.Split(out, "\n")
+	if len(lines) > limit {
+		rest := len(lines) - limit
+		lines = append(lines[:limit], fmt.Sprintf("... (truncated, %s more lines)", formatCount(int64(rest))))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeFenced は content をコードフェンスで囲んで書き込みます。
+// content がバッククォートの連続を含む場合は、それより長いフェンスを使用します。
+func writeFenced(b *bytes.Buffer, lang, content string) {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n", fence, lang, content, fence)
+}
+
+// closeFences は r の内容を読み込み、切り詰めによって閉じられていないコードフェンスがあれば閉じます。
+// 変換済みのノートブックはメモリ上にあるため、全体を読み込んで確認します。
+func closeFences(r io.Reader) io.Reader {
+	return &lazyReader{fn: func() (io.Reader, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		open := ""
+		for _, line := range strings.Split(string(data), "\n") {
+			switch {
+			case open == "" && strings.HasPrefix(line, "```"):
+				open = line[:len(line)-len(strings.TrimLeft(line, "`"))]
+			case open != "" && line == open:
+				open = ""
+			}
+		}
+		if open != "" {
+			if !bytes.HasSuffix(data, []byte("\n")) {
+				data = append(data, '\n')
+			}
+			data = append(data, open+"\n"...)
+		}
+		return bytes.NewReader(data), nil
+	}}
+}
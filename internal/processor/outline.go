@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"path"
+	"strings"
+)
+
+// outlineBody は省略した関数本体の代わりに出力する文字列です。
+const outlineBody = " { ... }"
+
+// isGoSource は name が Go のソースファイルかを返します。
+func isGoSource(name string) bool {
+	return strings.EqualFold(path.Ext(name), ".go")
+}
+
+// convertGoOutline は Go のソースをアウトラインに変換します。
+func convertGoOutline(data []byte, e *Entry) ([]byte, error) {
+	out, err := renderGoOutline(e.Path, data)
+	if err != nil {
+		return nil, err
+	}
+	e.Outline = true
+	return out, nil
+}
+
+// renderGoOutline は package 句、import、型宣言、関数・メソッドのシグネチャをドキュメントコメントとともに出力します。
+// 関数本体は { ... } に置き換えます。解析に失敗した場合はエラーを返します。
+func renderGoOutline(name string, src []byte) ([]byte, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if f.Doc != nil {
+		writeCommentGroup(&b, f.Doc)
+	}
+	b.WriteString("package " + f.Name.Name + "\n")
+
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	for _, decl := range f.Decls {
+		var node any
+		switch d := decl.(type) {
+		case *ast.GenDecl:
+			if d.Tok != token.IMPORT && d.Tok != token.TYPE {
+				continue
+			}
+			// 型宣言内のフィールド・メソッドのコメントも含めて出力する
+			node = &printer.CommentedNode{Node: d, Comments: commentsWithin(f.Comments, d)}
+		case *ast.FuncDecl:
+			// 本体を除いたシグネチャのみを出力する（ドキュメントコメントは Doc から出力される）
+			sig := *d
+			sig.Body = nil
+			node = &sig
+		default:
+			continue
+		}
+
+		b.WriteString("\n")
+		if err := cfg.Fprint(&b, fset, node); err != nil {
+			return nil, err
+		}
+		if _, ok := decl.(*ast.FuncDecl); ok {
+			b.WriteString(outlineBody)
+		}
+		b.WriteString("\n")
+	}
+	return b.Bytes(), nil
+}
+
+// commentsWithin は d の範囲（ドキュメントコメントを含む）にあるコメントを返します。
+func commentsWithin(comments []*ast.CommentGroup, d *ast.GenDecl) []*ast.CommentGroup {
+	start := d.Pos()
+	if d.Doc != nil {
+		start = d.Doc.Pos()
+	}
+	var in []*ast.CommentGroup
+	for _, c := range comments {
+		if c.Pos() >= start && c.End() <= d.End() {
+			in = append(in, c)
+		}
+	}
+	return in
+}
+
+// writeCommentGroup はコメントを元の記法のまま書き込みます。
+func writeCommentGroup(b *bytes.Buffer, g *ast.CommentGroup) {
+	for _, c := range g.List {
+		b.WriteString(c.Text + "\n")
+	}
+}
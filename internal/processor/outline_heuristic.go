@@ -0,0 +1,306 @@
+package processor
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// outlineFunc は内容をアウトラインに変換します。
+type outlineFunc func(src []byte) []byte
+
+// containerKeyword は本体を残す（メンバーのシグネチャを出力する）ブロックの宣言です。
+// これ以外のブロック（関数本体・オブジェクトリテラルなど）は { ... } に置き換えます。
+var containerKeyword = regexp.MustCompile(`\b(class|interface|struct|enum|union|trait|impl|mod|namespace|module|record|extern)\b`)
+
+// moduleClause は import { a, b } / export { a } のように、波括弧が名前の並びである文の先頭です。
+var moduleClause = regexp.MustCompile(`^\s*(import|export)(\s+type)?(\s+[\w$]+\s*,)?\s*$`)
+
+// heuristicOutliner は言語名の候補から、Go 以外の言語のアウトラインの方法を返します（非対応の場合は nil）。
+// 構文解析は行わず、括弧の対応（C 系）またはインデント（Python）から宣言を推定します。
+func heuristicOutliner(langs []string) outlineFunc {
+	for _, l := range langs {
+		switch l {
+		case "TypeScript", "TSX", "JavaScript":
+			return braceOutliner{syn: jsLike}.render
+		case "Java", "C", "C++", "C#", "Rust", "RenderScript", "Objective-C", "Objective-C++":
+			return braceOutliner{syn: cLike}.render
+		case "Python":
+			return outlinePython
+		}
+	}
+	return nil
+}
+
+// braceOutliner は波括弧の対応から関数本体などを { ... } に置き換えます。
+// 文字列リテラルとコメント内の括弧は数えません。コメント（ドキュメントコメント）は残ります。
+type braceOutliner struct {
+	syn *commentSyntax
+}
+
+func (o braceOutliner) render(src []byte) []byte {
+	var (
+		out       bytes.Buffer
+		header    []byte // 直前の ; { } 以降の、コメント・文字列を除いたコード（宣言の判定に使用）
+		collapsed int    // 省略中のブロックの深さ（0 の場合は出力中）
+	)
+
+	for i := 0; i < len(src); {
+		// コメント・文字列リテラルはまとめて読み進める
+		if n := o.skipLiteral(src[i:]); n > 0 {
+			if collapsed == 0 {
+				out.Write(src[i : i+n])
+				if !o.isComment(src[i:]) {
+					header = append(header, '"') // 文字列は宣言の判定に使わない
+				}
+			}
+			i += n
+			continue
+		}
+
+		c := src[i]
+		i++
+		if collapsed > 0 {
+			switch c {
+			case '{':
+				collapsed++
+			case '}':
+				collapsed--
+			}
+			continue
+		}
+
+		switch c {
+		case '{':
+			if isContainerHeader(header) {
+				out.WriteByte(c)
+			} else {
+				collapsed = 1
+				out.WriteString("{ ... }")
+			}
+			header = header[:0]
+			continue
+		case '}', ';':
+			header = header[:0]
+		default:
+			header = append(header, c)
+		}
+		out.WriteByte(c)
+	}
+	return out.Bytes()
+}
+
+// isContainerHeader は { の前の宣言が本体を残すブロック（class や struct など）かを返します。
+// 代入（オブジェクトリテラル）や引数リストを含む場合は関数などとみなします（struct を引数に取る C の関数など）。
+func isContainerHeader(header []byte) bool {
+	if moduleClause.Match(header) {
+		return true
+	}
+	return containerKeyword.Match(header) && !bytes.ContainsAny(header, "(=")
+}
+
+// skipLiteral は src の先頭のコメントまたは文字列リテラルの長さを返します（該当しない場合は 0）。
+func (o braceOutliner) skipLiteral(src []byte) int {
+	s := o.syn
+	for _, l := range s.line {
+		if bytes.HasPrefix(src, []byte(l)) {
+			if end := bytes.IndexByte(src, '\n'); end >= 0 {
+				return end
+			}
+			return len(src)
+		}
+	}
+	if s.blockStart != "" && bytes.HasPrefix(src, []byte(s.blockStart)) {
+		if end := bytes.Index(src[len(s.blockStart):], []byte(s.blockEnd)); end >= 0 {
+			return len(s.blockStart) + end + len(s.blockEnd)
+		}
+		return len(src)
+	}
+	for _, q := range s.quotes {
+		if !bytes.HasPrefix(src, []byte(q.delim)) {
+			continue
+		}
+		for j := len(q.delim); j < len(src); j++ {
+			switch {
+			case q.escapes && src[j] == '\\':
+				j++
+			case bytes.HasPrefix(src[j:], []byte(q.delim)):
+				return j + len(q.delim)
+			case src[j] == '\n' && !q.multiline:
+				return j
+			}
+		}
+		return len(src)
+	}
+	if s.charQuote && src[0] == '\'' {
+		return charLiteralLen(src)
+	}
+	return 0
+}
+
+// isComment は src がコメントで始まるかを返します。
+func (o braceOutliner) isComment(src []byte) bool {
+	for _, l := range o.syn.line {
+		if bytes.HasPrefix(src, []byte(l)) {
+			return true
+		}
+	}
+	return o.syn.blockStart != "" && bytes.HasPrefix(src, []byte(o.syn.blockStart))
+}
+
+// outlinePython は関数本体をドキュメント文字列と ... のみに置き換えます。
+// クラス本体は残すため、メソッドのシグネチャとクラス属性が出力されます。
+func outlinePython(src []byte) []byte {
+	lines := strings.SplitAfter(string(src), "\n")
+	var (
+		out      strings.Builder
+		inString string // 複数行の文字列の内側の場合はその引用符（文字列内の def は宣言ではない）
+	)
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		out.WriteString(line)
+		i++
+
+		code := strings.TrimSpace(line)
+		if inString != "" || (!strings.HasPrefix(code, "def ") && !strings.HasPrefix(code, "async def ")) {
+			inString = toggleTripleQuote(line, inString)
+			continue
+		}
+		indent := indentOf(line)
+
+		// 複数行にわたるシグネチャ（括弧が閉じ、: で終わるまで）
+		depth := bracketDepth(line)
+		for depth > 0 && i < len(lines) {
+			out.WriteString(lines[i])
+			depth += bracketDepth(lines[i])
+			line = lines[i]
+			i++
+		}
+		if !strings.HasSuffix(strings.TrimSpace(stripPythonComment(line)), ":") {
+			continue // def f(): return 1 のような1行の定義
+		}
+
+		// 本体の最初の文（ドキュメント文字列）の位置
+		j := i
+		for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+			j++
+		}
+		if j == len(lines) || len(indentOf(lines[j])) <= len(indent) {
+			continue
+		}
+		bodyIndent := indentOf(lines[j])
+		if first := strings.TrimSpace(lines[j]); startsPythonString(first) {
+			end := pythonStringEnd(lines, j)
+			for ; i <= end; i++ {
+				out.WriteString(lines[i])
+			}
+		}
+		out.WriteString(bodyIndent + "...\n")
+
+		// 本体の残りを読み飛ばす（インデントの浅い行が現れるまで。複数行の文字列内は浅くてもよい）
+		// 本体の後ろの空行は次の宣言との区切りとして残す
+		inString, blanks := "", 0
+		for i < len(lines) {
+			l := lines[i]
+			if inString == "" && strings.TrimSpace(l) == "" {
+				blanks++
+			} else if inString == "" && len(indentOf(l)) <= len(indent) {
+				break
+			} else {
+				blanks = 0
+			}
+			inString = toggleTripleQuote(l, inString)
+			i++
+		}
+		i -= blanks
+	}
+	return []byte(out.String())
+}
+
+// indentOf は行頭の空白を返します。
+func indentOf(line string) string {
+	return line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+}
+
+// bracketDepth は行内の括弧の開閉の差を返します（文字列・コメントは簡易的に除外）。
+func bracketDepth(line string) int {
+	depth := 0
+	for _, c := range stripPythonComment(line) {
+		switch c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+		}
+	}
+	return depth
+}
+
+// stripPythonComment は文字列外の # 以降を取り除きます。
+func stripPythonComment(line string) string {
+	var quote rune
+	for i, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// startsPythonString は文が文字列リテラル（ドキュメント文字列）で始まるかを返します。
+func startsPythonString(s string) bool {
+	s = strings.TrimLeft(s, "rRbBuU")
+	return strings.HasPrefix(s, `"`) || strings.HasPrefix(s, `'`)
+}
+
+// pythonStringEnd は lines[start] から始まる文字列リテラルが終わる行の位置を返します。
+func pythonStringEnd(lines []string, start int) int {
+	s := strings.TrimLeft(strings.TrimSpace(lines[start]), "rRbBuU")
+	for _, q := range []string{`"""`, `'''`} {
+		if !strings.HasPrefix(s, q) {
+			continue
+		}
+		if strings.Contains(s[len(q):], q) {
+			return start
+		}
+		for i := start + 1; i < len(lines); i++ {
+			if strings.Contains(lines[i], q) {
+				return i
+			}
+		}
+		return len(lines) - 1
+	}
+	return start
+}
+
+// toggleTripleQuote は行内の三重引用符によって、複数行の文字列の内外を切り替えます。
+func toggleTripleQuote(line, inString string) string {
+	for {
+		if inString != "" {
+			i := strings.Index(line, inString)
+			if i < 0 {
+				return inString
+			}
+			line, inString = line[i+3:], ""
+			continue
+		}
+		i, q := -1, ""
+		for _, cand := range []string{`"""`, `'''`} {
+			if j := strings.Index(line, cand); j >= 0 && (i < 0 || j < i) {
+				i, q = j, cand
+			}
+		}
+		if i < 0 {
+			return ""
+		}
+		line, inString = line[i+3:], q
+	}
+}
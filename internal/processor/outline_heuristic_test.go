@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the .golden files in testdata")
+
+// TestHeuristicOutline は testdata/outline の各言語の入力を、同名の .golden ファイルと比較します。
+// go test -run TestHeuristicOutline -update で .golden ファイルを更新します。
+func TestHeuristicOutline(t *testing.T) {
+	tests := []struct {
+		file string
+		lang string
+	}{
+		{"sample.ts", "TypeScript"},
+		{"sample.py", "Python"},
+		{"Sample.java", "Java"},
+		{"sample.rs", "Rust"},
+		{"sample.c", "C"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			name := filepath.Join("testdata", "outline", tt.file)
+			src, err := os.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			outline := heuristicOutliner([]string{tt.lang})
+			if outline == nil {
+				t.Fatalf("no outliner for %s", tt.lang)
+			}
+			got := outline(src)
+
+			golden := name + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("outline of %s differs from %s\n--- got ---\n%s\n--- want ---\n%s", tt.file, golden, got, want)
+			}
+		})
+	}
+}
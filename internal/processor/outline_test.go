@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestGoOutline は testdata/outline/sample.go のアウトラインを sample.go.golden と比較します。
+// go test -run TestGoOutline -update で .golden ファイルを更新します。
+func TestGoOutline(t *testing.T) {
+	name := filepath.Join("testdata", "outline", "sample.go")
+	src, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderGoOutline(name, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := name + ".golden"
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("outline of %s differs from %s\n--- got ---\n%s\n--- want ---\n%s", name, golden, got, want)
+	}
+}
+
+func TestExecuteGoOutline(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":   {Data: []byte("package main\n\nfunc main() {\n\tprintln(\"body\")\n}\n")},
+		"broken.go": {Data: []byte("package main\n\nfunc broken( {\n")},
+	}
+	out, _ := execute(t, fsys, Options{Outline: true})
+
+	main := fileSection(out, "main.go")
+	if !strings.Contains(main, "(Outline: function bodies omitted)\n") || !strings.Contains(main, "func main() { ... }\n") {
+		t.Errorf("main.go was not outlined:\n%s", main)
+	}
+	// 解析できない場合は元の内容をそのまま出力する
+	broken := fileSection(out, "broken.go")
+	if strings.Contains(broken, "(Outline") || !strings.Contains(broken, "func broken( {\n") {
+		t.Errorf("broken.go was not written as is:\n%s", broken)
+	}
+}
@@ -3,117 +3,377 @@ package processor
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"net/http"
-	"os"
+	"log/slog"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/kazuki-sk/codepack/internal/gitattr"
 	"github.com/kazuki-sk/codepack/internal/ignorer"
 	"github.com/kazuki-sk/codepack/internal/language"
-	"github.com/kazuki-sk/codepack/internal/output"
+	"github.com/kazuki-sk/codepack/internal/secret"
 )
 
 // DefaultThreshold は大容量ファイルとみなす閾値（500KB）です。
 const DefaultThreshold = 500 * 1024
 
+// DiffStyle は差分の出力形式を表します。
+type DiffStyle int
+
+const (
+	// DiffInline は各ファイルの内容の直後に差分ブロックを出力します。
+	DiffInline DiffStyle = iota
+	// DiffCombined は全ファイルの出力後に、単一のパッチセクションとしてまとめて出力します。
+	DiffCombined
+)
+
+// Options は Processor の設定と依存コンポーネントを保持します。
+type Options struct {
+	// TargetDir は fsys に対応するディスク上のディレクトリです（ディスク以外のソースでは空）。
+	// OutputFile と合わせて自己参照除外の判定に使用します。
+	TargetDir string
+	// OutputFile は出力先のファイルパスです（自己参照除外判定に使用。ファイル出力しない場合は空）。
+	OutputFile string
+
+	Ignorer          *ignorer.Ignorer
+	Mapper           *language.Mapper
+	LargeFileHandler LargeFileHandler
+	// Formatter はエントリの書式です。nil の場合は MarkdownFormatter を使用します。
+	Formatter Formatter
+	// Threshold は大容量ファイルとみなすサイズです。0 の場合は DefaultThreshold です。
+	Threshold int64
+	// SizeOverrides はパターンごとの閾値です。複数一致した場合は後に指定したものが優先されます。
+	SizeOverrides []SizeRule
+	// TextPatterns / BinaryPatterns に一致するファイルは、内容によらずテキスト / バイナリとして扱います。
+	// パターンの形式は SizeRule.Pattern と同じです。両方に一致する場合は TextPatterns が優先されます。
+	TextPatterns   []string
+	BinaryPatterns []string
+
+	// Attributes は .gitattributes による属性です（nil の場合は属性なしとして扱います）。
+	// binary・-diff / diff をバイナリの判定に、linguist-generated / linguist-vendored を SkipGenerated に使用します。
+	Attributes *gitattr.Attributes
+
+	// SkipGenerated が true の場合、生成されたファイル（"Code generated ... DO NOT EDIT" などの目印、
+	// .gitattributes の linguist-generated / linguist-vendored）を出力しません。
+	SkipGenerated bool
+	// GeneratedPlaceholder が true の場合、SkipGenerated で除外するファイルを見出しのみのエントリとして出力します。
+	GeneratedPlaceholder bool
+
+	// HexDumpBytes はバイナリファイルの先頭をダンプするバイト数です。0 の場合はダンプしません。
+	HexDumpBytes int
+
+	// StripComments が true の場合、言語ごとの構文に従ってコメントを取り除きます（文字列リテラル内は残します）。
+	StripComments bool
+	// KeepDocComments が true の場合、StripComments でもドキュメントコメントを残します。
+	KeepDocComments bool
+	// CollapseBlankLines が true の場合、連続する空行を1行にまとめます。
+	CollapseBlankLines bool
+
+	// Outline が true の場合、Go のソースを宣言のシグネチャとドキュメントコメントのみに要約します。
+	// TypeScript/JavaScript・Python・Java・Rust・C/C++/C# は括弧の対応やインデントから推定して関数本体を省略します。
+	Outline bool
+
+	// Secrets が nil でない場合、内容と差分の秘密情報（API キー・トークン・秘密鍵など）を [REDACTED:rule-id] に置き換えます。
+	// 置き換えは他の変換より先に行い、Stats.Redactions に記録します。
+	Secrets *secret.Scanner
+	// FailOnSecret が true の場合、秘密情報を1つでも置き換えれば走査完了後に ErrSecretsFound を返します。
+	FailOnSecret bool
+
+	// Replacements は内容を書き換える規則です（社内のホスト名や顧客名の除去など）。
+	// 秘密情報の置き換えの後、他の変換より先に、指定順に1行ずつ適用します。差分のパッチにも適用します。
+	Replacements []Replacement
+
+	// LineNumbers が true の場合、内容の各行の先頭に右揃えの行番号を付けます。
+	// ノートブックの変換やアウトラインなど、内容全体を変換したファイルには付けません（元の行と対応しないため）。
+	// 書式は Formatter が LineNumberFormatter を実装していればそれに従います。
+	LineNumbers bool
+
+	// RawNotebooks が true の場合、Jupyter ノートブック (.ipynb) を変換せず JSON のまま出力します。
+	RawNotebooks bool
+	// NotebookOutputLines はノートブックのセルごとに残す出力の行数です。
+	// 0 の場合は DefaultNotebookOutputLines、負の場合は出力を含めません。
+	NotebookOutputLines int
+
+	// Truncation は大容量ファイルを切り詰める場合の設定です。ゼロ値は先頭 DefaultTruncateLines 行です。
+	Truncation Truncation
+
+	// Preamble はファイルの前に書き込むテキスト（依存関係の一覧など）です。空の場合は書き込みません。
+	Preamble string
+	// Snippets は走査したファイルの後に出力する、ファイルの一部（行の範囲）です。
+	Snippets []Snippet
+	// SnippetsOnly が true の場合、fsys を走査せず Snippets のみを出力します。
+	SnippetsOnly bool
+
+	// Diffs は差分の提供元です。nil の場合は差分を出力しません。
+	Diffs     DiffProvider
+	DiffStyle DiffStyle
+
+	// OnVisit は除外判定の前に、走査した各ファイルのパスで呼ばれるフックです（任意）。
+	OnVisit func(path string)
+	// OnEntry は各エントリの書き込み完了後に呼ばれるフックです（任意）。
+	OnEntry func(Entry)
+
+	// Logger はスキップ理由などの記録先です。nil の場合は出力しません。
+	Logger *slog.Logger
+	// Strict が true の場合、読み込めないファイルが1つでもあれば走査完了後に ErrUnreadable を返します。
+	Strict bool
+}
+
+// SizeRule はパターンに一致するファイルの閾値です。
+// Pattern は path.Match の形式で、'/' を含む場合はルートからの相対パス全体、含まない場合はファイル名と照合します。
+type SizeRule struct {
+	Pattern string
+	Size    int64
+}
+
+// ErrUnreadable は Strict モードで読み込めないファイルがあったことを示すエラーです。
+var ErrUnreadable = errors.New("some files could not be read")
+
+// SkipReason は出力から除外された理由を表します。
+type SkipReason string
+
+const (
+	SkipIgnored    SkipReason = "ignored"    // 除外ルールに一致
+	SkipLarge      SkipReason = "large"      // 大容量ファイルの取り込みを拒否
+	SkipUnreadable SkipReason = "unreadable" // 権限エラーや読み込みエラー
+	SkipSymlink    SkipReason = "symlink"    // シンボリックリンク（仕様 3.3）
+	SkipOutput     SkipReason = "output"     // 出力ファイル自身
+	SkipGenerated  SkipReason = "generated"  // 生成されたファイル（SkipGenerated の指定時）
+)
+
+// Skip は出力から除外された1エントリの記録です。
+type Skip struct {
+	Path   string     `json:"path"`
+	Dir    bool       `json:"dir,omitempty"`
+	Reason SkipReason `json:"reason"`
+	Detail string     `json:"detail,omitempty"` // 除外ルールの出所、またはエラー内容
+}
+
+// Stats は実行結果の集計です。
+type Stats struct {
+	Files        int           `json:"files"`         // 内容を出力したファイル数
+	Binary       int           `json:"binary"`        // バイナリとして内容をスキップしたファイル数
+	IgnoredFiles int           `json:"ignored_files"` // 除外ルールに一致したファイル数
+	IgnoredDirs  int           `json:"ignored_dirs"`  // 除外ルールに一致したディレクトリ数（配下は走査しない）
+	LargeSkipped int           `json:"large_skipped"` // 取り込みを拒否した大容量ファイル数
+	Truncated    int           `json:"truncated"`     // 先頭のみを出力した大容量ファイル数
+	Unreadable   int           `json:"unreadable"`    // 読み込めなかったファイル数
+	Generated    int           `json:"generated"`     // 生成されたファイルとして内容を省略したファイル数
+	Redacted     int           `json:"redacted"`      // 置き換えた秘密情報の数
+	Bytes        int64         `json:"bytes"`         // 出力した総バイト数（見出し等を含む）
+	Tokens       int64         `json:"tokens"`        // 推定トークン数（Bytes / 4）
+	Elapsed      time.Duration `json:"elapsed_ns"`
+	Skipped      []Skip        `json:"skipped"`
+	Redactions   []Redaction   `json:"redactions,omitempty"`
+}
+
+// EstimateTokens はバイト数から LLM のトークン数を概算します。
+// 英語・ソースコードでおおよそ4バイト/トークンとする一般的な目安に基づきます。
+func EstimateTokens(n int64) int64 {
+	return (n + 3) / 4
+}
+
 // Processor はファイルシステムの走査とコンテンツ処理を行うコアロジックです。
 type Processor struct {
-	targetDir        string
-	absOutputPath    string // 自己参照防止用の絶対パス
-	ignorer          *ignorer.Ignorer
-	mapper           *language.Mapper
-	output           output.Strategy
-	largeFileHandler LargeFileHandler
+	fsys          fs.FS
+	opts          Options
+	absOutputPath string // 自己参照防止用の絶対パス（判定不要な場合は空）
+	output        *countingWriter
+	formatter     Formatter
+	log           *slog.Logger
+	stats         Stats
 }
 
 // NewProcessor はProcessorを初期化します。
-// outputFile: 出力先のファイルパス（自己参照除外判定に使用）
-func NewProcessor(
-	targetDir string,
-	outputFile string,
-	ignr *ignorer.Ignorer,
-	mpr *language.Mapper,
-	out output.Strategy,
-	lfh LargeFileHandler,
-) (*Processor, error) {
+// fsys: 走査対象のファイルシステム
+// out: 出力先。Close (Flush) は呼び出し元の責務です。
+func NewProcessor(fsys fs.FS, out io.Writer, opts Options) (*Processor, error) {
+	if opts.Ignorer == nil || opts.Mapper == nil || opts.LargeFileHandler == nil {
+		return nil, errors.New("processor: Ignorer, Mapper and LargeFileHandler are required")
+	}
+
 	// 出力ファイルの絶対パスを解決して保持（存在しなくてもパス比較は可能）
-	absOut, err := filepath.Abs(outputFile)
-	if err != nil {
-		return nil, fmt.Errorf("failed to resolve output file path: %w", err)
+	var absOut string
+	if opts.TargetDir != "" && opts.OutputFile != "" {
+		var err error
+		absOut, err = filepath.Abs(opts.OutputFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve output file path: %w", err)
+		}
+	}
+
+	formatter := opts.Formatter
+	if formatter == nil {
+		formatter = MarkdownFormatter{}
+	}
+
+	logger := opts.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
 	}
 
 	return &Processor{
-		targetDir:        targetDir,
-		absOutputPath:    absOut,
-		ignorer:          ignr,
-		mapper:           mpr,
-		output:           out,
-		largeFileHandler: lfh,
+		fsys:          fsys,
+		opts:          opts,
+		absOutputPath: absOut,
+		output:        &countingWriter{w: out},
+		formatter:     formatter,
+		log:           logger,
 	}, nil
 }
 
-// Execute は対象ディレクトリの走査とMarkdown生成を実行します。
+// Execute は対象ファイルシステムの走査とMarkdown生成を実行します。
+// パスは fsys のルートからの相対パス（スラッシュ区切り）として扱います。
 //
 // Note: 本メソッドは `Output Strategy` への書き込み完了までを責務としますが、
 // Outputの `Close` (Flush) 処理は呼び出し元（main）の責務です。
-func (p *Processor) Execute(ctx context.Context) error {
-	err := filepath.WalkDir(p.targetDir, func(path string, d fs.DirEntry, err error) error {
+func (p *Processor) Execute(ctx context.Context) (Stats, error) {
+	start := time.Now()
+	err := p.walk(ctx)
+	p.stats.Bytes = p.output.n
+	p.stats.Tokens = EstimateTokens(p.output.n)
+	p.stats.Elapsed = time.Since(start)
+
+	if err == nil && p.opts.Strict && p.stats.Unreadable > 0 {
+		err = fmt.Errorf("%w: %d file(s)", ErrUnreadable, p.stats.Unreadable)
+	}
+	if err == nil {
+		err = p.secretsError()
+	}
+	return p.stats, err
+}
+
+// skip は除外の記録と集計を行います。
+func (p *Processor) skip(path string, isDir bool, reason SkipReason, detail string) {
+	switch reason {
+	case SkipIgnored:
+		if isDir {
+			p.stats.IgnoredDirs++
+		} else {
+			p.stats.IgnoredFiles++
+		}
+	case SkipLarge:
+		p.stats.LargeSkipped++
+	case SkipUnreadable:
+		p.stats.Unreadable++
+	case SkipGenerated:
+		p.stats.Generated++
+	}
+	p.stats.Skipped = append(p.stats.Skipped, Skip{Path: path, Dir: isDir, Reason: reason, Detail: detail})
+
+	// 除外ルールによる通常のスキップは詳細表示時のみ、取りこぼしにつながるものは警告とする
+	level := slog.LevelDebug
+	switch reason {
+	case SkipLarge:
+		level = slog.LevelInfo
+	case SkipUnreadable:
+		level = slog.LevelWarn
+	}
+	attrs := []any{"path", path, "reason", string(reason)}
+	if isDir {
+		attrs = append(attrs, "dir", true)
+	}
+	if detail != "" {
+		attrs = append(attrs, "detail", detail)
+	}
+	p.log.Log(context.Background(), level, "skipped", attrs...)
+}
+
+// walk は Preamble、fsys の各ファイル、Snippets の順に出力します。
+func (p *Processor) walk(ctx context.Context) error {
+	if p.opts.Preamble != "" {
+		if _, err := io.WriteString(p.output, p.opts.Preamble); err != nil {
+			return err
+		}
+	}
+
+	if !p.opts.SnippetsOnly {
+		if err := p.walkFiles(ctx); err != nil {
+			return err
+		}
+	}
+	if err := p.writeSnippets(ctx); err != nil {
+		return err
+	}
+
+	// 差分をまとめて出力する形式の場合は、最後にパッチセクションを書き込む
+	if p.opts.Diffs != nil && p.opts.DiffStyle == DiffCombined {
+		return p.writeCombinedDiff(ctx)
+	}
+
+	return nil
+}
+
+// walkFiles は fsys を走査し、除外されない各ファイルを処理します。
+func (p *Processor) walkFiles(ctx context.Context) error {
+	return fs.WalkDir(p.fsys, ".", func(path string, d fs.DirEntry, err error) error {
 		// 1. キャンセルチェック: ユーザーの中断シグナルを検知したら即座に終了
 		if err := ctx.Err(); err != nil {
 			return err
 		}
 
 		if err != nil {
-			// アクセス権限エラーなどはスキップして続行
-			// ログ機構があればここでWarnログを出力
+			// アクセス権限エラーなどは記録してスキップし、続行
+			p.skip(path, d != nil && d.IsDir(), SkipUnreadable, err.Error())
 			return nil
 		}
 
 		// 2. ディレクトリの処理
 		if d.IsDir() {
-			if p.ignorer.ShouldIgnore(path, true) {
-				return filepath.SkipDir
+			if ignored, source := p.opts.Ignorer.Explain(path, true); ignored {
+				p.skip(path, true, SkipIgnored, source)
+				return fs.SkipDir
 			}
 			return nil
 		}
 
+		if p.opts.OnVisit != nil {
+			p.opts.OnVisit(path)
+		}
+
 		// 3. シンボリックリンクのスキップ（仕様 3.3）
 		info, err := d.Info()
 		if err != nil {
+			p.skip(path, false, SkipUnreadable, err.Error())
 			return nil
 		}
-		if info.Mode()&os.ModeSymlink != 0 {
+		if info.Mode()&fs.ModeSymlink != 0 {
+			p.skip(path, false, SkipSymlink, "")
 			return nil
 		}
 
 		// 4. 自己参照チェック（仕様 3.2/3.3）
-		// 出力ファイル自体を読み込まないように除外
-		absPath, err := filepath.Abs(path)
-		if err == nil && absPath == p.absOutputPath {
-			return nil
+		// 出力ファイル自体を読み込まないように除外（ディスク上のソースのみ）
+		if p.absOutputPath != "" {
+			absPath, err := filepath.Abs(filepath.Join(p.opts.TargetDir, filepath.FromSlash(path)))
+			if err == nil && absPath == p.absOutputPath {
+				p.skip(path, false, SkipOutput, "")
+				return nil
+			}
 		}
 
 		// 5. ファイルの除外判定
-		if p.ignorer.ShouldIgnore(path, false) {
+		if ignored, source := p.opts.Ignorer.Explain(path, false); ignored {
+			p.skip(path, false, SkipIgnored, source)
 			return nil
 		}
 
 		// 6. ファイル処理の実行
 		return p.processFile(ctx, path, info)
 	})
-
-	return err
 }
 
 // processFile は単一ファイルの読み込み、判定、出力を行います。
 func (p *Processor) processFile(ctx context.Context, path string, info fs.FileInfo) error {
 	// ファイルオープン
-	file, err := os.Open(path)
+	file, err := p.fsys.Open(path)
 	if err != nil {
-		return nil // 読み込み不可ファイルはスキップ
+		p.skip(path, false, SkipUnreadable, err.Error()) // 読み込み不可ファイルはスキップ
+		return nil
 	}
 	defer file.Close()
 
@@ -122,74 +382,306 @@ func (p *Processor) processFile(ctx context.Context, path string, info fs.FileIn
 		return err
 	}
 
-	// A. バイナリ判定（仕様準拠: io.LimitReader使用）
+	// A. バイナリ判定・文字コード判定（仕様準拠: io.LimitReader使用）
 	// 先頭512バイトまでを読み込む。512バイト未満の場合はEOFまでのデータが返る。
 	headBuf, err := io.ReadAll(io.LimitReader(file, 512))
 	if err != nil {
+		p.skip(path, false, SkipUnreadable, err.Error())
 		return nil
 	}
 
-	if isBinary(headBuf) {
+	entry := Entry{Path: path, Size: info.Size()}
+
+	// 読み込んだheadBufと、続きのfileストリームを保持する（文字コード判定やプレビューで先読みされる場合がある）
+	src := &peekSource{buf: headBuf, r: file}
+
+	// 判定には先頭 encodingSampleSize バイトまでを使用する（先読み分は出力時にそのまま使用される）
+	sample, err := src.peek(encodingSampleSize)
+	if err != nil {
+		p.skip(path, false, SkipUnreadable, err.Error())
+		return nil
+	}
+	if p.opts.SkipGenerated {
+		if generated, by := p.generated(path, sample); generated {
+			if !p.opts.GeneratedPlaceholder {
+				p.skip(path, false, SkipGenerated, by)
+				return nil
+			}
+			entry.Generated = by
+			return p.writeEntry(ctx, entry, nil)
+		}
+	}
+	if binary, by := p.classify(path, sample); binary {
 		// バイナリの場合はパスのみ記録（プレースホルダー出力）
-		return p.writeMarkdownEntry(ctx, path, "", nil, true)
+		entry.Binary = true
+		p.log.Debug("binary file, content skipped", "path", path, "reason", "binary", "by", by)
+		binInfo, err := p.inspectBinary(ctx, src, sample)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			p.skip(path, false, SkipUnreadable, err.Error())
+			return nil
+		}
+		entry.BinaryInfo = binInfo
+		return p.writeEntry(ctx, entry, nil)
+	}
+	enc := p.detectEncoding(path, sample, info.Size())
+	entry.Encoding = enc.name
+	entry.Language = p.opts.Mapper.GetLanguage(path)
+
+	// ノートブックのセルへの変換やアウトラインなど、内容全体を変換する場合は変換後のサイズで大容量判定を行う
+	// （ノートブックは出力の base64 画像などで元の JSON が大きくなりがちなため）
+	// 変換に失敗した場合と、maxConvertSize を超えるため変換しない場合は元の内容をそのまま出力する
+	size := info.Size()
+	converted := false
+	if convert := p.converter(path); convert != nil {
+		decoded := enc.reader(src.reader())
+		data, err := io.ReadAll(io.LimitReader(decoded, maxConvertSize+1))
+		if err != nil {
+			p.skip(path, false, SkipUnreadable, err.Error())
+			return nil
+		}
+		if len(data) > maxConvertSize {
+			p.log.Debug("too large to convert, packing as-is", "path", path, "size", size)
+			src = &peekSource{r: io.MultiReader(bytes.NewReader(data), decoded)}
+		} else if out, err := convert(data, &entry); err != nil {
+			p.log.Debug("conversion failed, packing as-is", "path", path, "error", err)
+			src, size = &peekSource{r: bytes.NewReader(data)}, int64(len(data))
+		} else {
+			converted = true
+			src, size = &peekSource{r: bytes.NewReader(out)}, int64(len(out))
+		}
+		// 文字コードは変換済みのため、以降は UTF-8 として扱う
+		enc = encUTF8
 	}
 
 	// B. サイズ制限判定
-	if info.Size() > DefaultThreshold {
-		include, err := p.largeFileHandler.ShouldInclude(ctx, path, info.Size())
+	truncate := false
+	if size > p.threshold(path) {
+		decision, err := p.decideLarge(ctx, LargeFile{
+			Path:       path,
+			Size:       size,
+			Truncation: p.opts.Truncation,
+			src:        src,
+			enc:        enc,
+		})
 		if err != nil {
 			return err
 		}
-		if !include {
-			return nil // ユーザーまたは設定により除外
+		switch decision {
+		case LargeSkip:
+			p.skip(path, false, SkipLarge, "") // ユーザーまたは設定により除外
+			return nil
+		case LargeTruncate:
+			truncate = true
 		}
 	}
 
 	// C. コンテンツ出力
-	// 読み込んだheadBufと、続きのfileストリームを結合して渡す
-	reader := io.MultiReader(bytes.NewReader(headBuf), file)
-	lang := p.mapper.GetLanguage(path)
+	// 先読み済みの内容と、続きのfileストリームを結合して渡す
+	reader := enc.reader(src.reader())
+	filters := p.lineFilters(path, entry)
+	if p.opts.LineNumbers && !converted {
+		// 行番号は他の変換の前の行で数え、切り詰めの省略表示には付けない
+		entry.LineNumbers = true
+		filters = []lineFilter{p.newLineNumberer(minNumberWidth, filters...)}
+	}
+	if len(filters) > 0 {
+		reader = newLineFilterReader(reader, filters...)
+	}
+	if truncate {
+		reader = p.opts.Truncation.reader(reader)
+		entry.Truncated = true
+		if entry.Notebook {
+			// セルのフェンスの途中で切れた場合に、以降の出力が崩れないようにする
+			reader = closeFences(reader)
+		}
+	}
+
+	return p.writeEntry(ctx, entry, reader)
+}
 
-	return p.writeMarkdownEntry(ctx, path, lang, reader, false)
+// lineFilters は内容に適用する行単位の変換を返します。
+// 秘密情報はコメント内にも含まれうるため、コメントの除去より先に置き換えます。
+func (p *Processor) lineFilters(name string, e Entry) []lineFilter {
+	var filters []lineFilter
+	if r := p.redactor(name); r != nil {
+		filters = append(filters, r)
+	}
+	return append(filters, p.transformFilters(name, e)...)
 }
 
-// writeMarkdownEntry はMarkdown形式のフォーマットと出力を行います。
-// 本来はOutput層の責務ですが、既存インターフェース制約のためここでフォーマットし、
-// Output Strategyにはバイトストリームとして書き込みます。
-func (p *Processor) writeMarkdownEntry(ctx context.Context, path, lang string, r io.Reader, isBinarySkipped bool) error {
-	// パス区切り文字の統一（仕様 3.3）
-	relPath, err := filepath.Rel(p.targetDir, path)
+// transformFilters は秘密情報の置き換えを除く、行単位の変換を返します。
+func (p *Processor) transformFilters(name string, e Entry) []lineFilter {
+	var filters []lineFilter
+	if rules := p.replacementsFor(name); len(rules) > 0 {
+		filters = append(filters, &replaceFilter{rules: rules})
+	}
+	// 変換済みのノートブックは Markdown のため、コメントの除去は行わない
+	if p.opts.StripComments && !e.Notebook {
+		if syn := commentSyntaxFor(p.opts.Mapper.Languages(name)); syn != nil {
+			filters = append(filters, newCommentStripper(syn, p.opts.KeepDocComments))
+		}
+	}
+	if p.opts.CollapseBlankLines {
+		filters = append(filters, &blankCollapser{})
+	}
+	return filters
+}
+
+// newLineNumberer は Formatter の書式で行番号を付ける lineFilter を作成します。
+func (p *Processor) newLineNumberer(width int, filters ...lineFilter) *lineNumberer {
+	format := defaultLineNumber
+	if f, ok := p.formatter.(LineNumberFormatter); ok {
+		format = f.FormatLineNumber
+	}
+	return &lineNumberer{width: width, format: format, filters: filters}
+}
+
+// maxConvertSize は内容全体を変換（ノートブック・アウトライン）するファイルの最大サイズです（UTF-8 に変換後のバイト数）。
+// 変換では内容全体をメモリに読み込むため、これを超えるファイルは変換せずに大容量判定を行います。
+const maxConvertSize = 32 << 20
+
+// converter は内容全体の変換が必要なファイルの変換関数を返します（不要な場合は nil）。
+// 変換関数は成功した場合のみ e を更新します。
+func (p *Processor) converter(name string) func(data []byte, e *Entry) ([]byte, error) {
+	switch {
+	case isNotebook(name) && !p.opts.RawNotebooks:
+		return p.convertNotebook
+	case p.opts.Outline && isGoSource(name):
+		return convertGoOutline
+	case p.opts.Outline:
+		if outline := heuristicOutliner(p.opts.Mapper.Languages(name)); outline != nil {
+			return func(data []byte, e *Entry) ([]byte, error) {
+				e.Outline = true
+				return outline(data), nil
+			}
+		}
+	}
+	return nil
+}
+
+// convertNotebook はノートブックをセルの並びに変換します。
+func (p *Processor) convertNotebook(data []byte, e *Entry) ([]byte, error) {
+	rendered, lang, err := renderNotebook(data, p.notebookOutputLines())
 	if err != nil {
-		relPath = path
+		return nil, err
+	}
+	e.Notebook = true
+	e.Language = lang
+	return rendered, nil
+}
+
+// notebookOutputLines はノートブックのセルごとに残す出力の行数を返します（負の場合は出力を含めない）。
+func (p *Processor) notebookOutputLines() int {
+	if p.opts.NotebookOutputLines == 0 {
+		return DefaultNotebookOutputLines
 	}
-	normalizedPath := filepath.ToSlash(relPath)
+	return p.opts.NotebookOutputLines
+}
 
-	var header string
-	if isBinarySkipped {
-		header = fmt.Sprintf("\n## File: %s\n\n(Binary file skipped)\n", normalizedPath)
-	} else {
-		header = fmt.Sprintf("\n## File: %s\n\n```%s\n", normalizedPath, lang)
+// decideLarge は LargeFileHandler に大容量ファイルの扱いを問い合わせます。
+// LargeFileDecider を実装していれば切り詰めを含む判断を、そうでなければ含める/除外の判断を使用します。
+func (p *Processor) decideLarge(ctx context.Context, f LargeFile) (LargeFileDecision, error) {
+	if d, ok := p.opts.LargeFileHandler.(LargeFileDecider); ok {
+		return d.DecideLarge(ctx, f)
+	}
+	include, err := p.opts.LargeFileHandler.ShouldInclude(ctx, f.Path, f.Size)
+	if err != nil || !include {
+		return LargeSkip, err
 	}
+	return LargeInclude, nil
+}
 
-	// ヘッダー書き込み
-	if _, err := p.output.Write([]byte(header)); err != nil {
+// threshold は path に適用する大容量ファイルの閾値を返します。
+func (p *Processor) threshold(name string) int64 {
+	limit := p.opts.Threshold
+	if limit <= 0 {
+		limit = DefaultThreshold
+	}
+	for _, rule := range p.opts.SizeOverrides {
+		if matchPattern(rule.Pattern, name) {
+			limit = rule.Size
+		}
+	}
+	return limit
+}
+
+// writeEntry は Formatter による装飾と内容のストリーミング出力を行います。
+// r はバイナリとしてスキップする場合 nil です。
+func (p *Processor) writeEntry(ctx context.Context, e Entry, r io.Reader) error {
+	// ヘッダー書き込み（表示するパスのみ書き換え、集計やフックには元のパスを渡す）
+	header := e
+	header.Path = p.displayPath(e.Path)
+	if err := p.formatter.WriteHeader(p.output, header); err != nil {
 		return err
 	}
 
 	// コンテンツ書き込み（バイナリスキップでない場合）
-	if !isBinarySkipped && r != nil {
+	if !e.Binary && r != nil {
 		if err := p.copyCancellable(ctx, p.output, r); err != nil {
 			return err
 		}
 		// フッター書き込み
-		if _, err := p.output.Write([]byte("\n```\n")); err != nil {
+		if err := p.formatter.WriteFooter(p.output, header); err != nil {
 			return err
 		}
 	}
 
+	// 差分ブロックの書き込み（インライン形式の場合。ファイルの一部の場合は同じ差分の繰り返しになるため省略）
+	if p.opts.Diffs != nil && p.opts.DiffStyle == DiffInline && e.StartLine == 0 {
+		if patch, ok := p.opts.Diffs.Patch(e.Path); ok {
+			patch = p.replacePatch(e.Path, p.redactPatch(e.Path, patch))
+			if err := p.formatter.WriteDiff(p.output, header.Path, p.opts.Diffs.Ref(), patch); err != nil {
+				return err
+			}
+		}
+	}
+
+	switch {
+	case e.Binary:
+		p.stats.Binary++
+	case e.Generated != "":
+		p.stats.Generated++
+	default:
+		p.stats.Files++
+		if e.Truncated {
+			p.stats.Truncated++
+		}
+		if e.Encoding != "" {
+			p.log.Debug("packed", "path", e.Path, "size", e.Size, "language", e.Language, "encoding", e.Encoding)
+		} else {
+			p.log.Debug("packed", "path", e.Path, "size", e.Size, "language", e.Language)
+		}
+	}
+	if p.opts.OnEntry != nil {
+		p.opts.OnEntry(e)
+	}
 	return nil
 }
 
+// writeCombinedDiff は全ファイルの差分を単一のパッチセクションとして書き込みます。
+// 走査対象外（削除済み・除外済み）のファイルの差分も含まれます。
+func (p *Processor) writeCombinedDiff(ctx context.Context) error {
+	paths := p.opts.Diffs.Paths()
+	if len(paths) == 0 {
+		return nil
+	}
+
+	var patch strings.Builder
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if text, ok := p.opts.Diffs.Patch(path); ok {
+			patch.WriteString(p.replacePatch(path, p.redactPatch(path, text)))
+		}
+	}
+
+	return p.formatter.WriteDiff(p.output, "", p.opts.Diffs.Ref(), patch.String())
+}
+
 // copyCancellable は io.Copy の代わりに使用し、Contextのキャンセルを検知しながらコピーを行います。
 // これにより、大容量ファイル書き込み中の即時中断が可能になります。
 func (p *Processor) copyCancellable(ctx context.Context, dst io.Writer, src io.Reader) error {
@@ -221,18 +713,14 @@ func (p *Processor) copyCancellable(ctx context.Context, dst io.Writer, src io.R
 	}
 }
 
-// isBinary はバイト列からバイナリかどうかを判定します。
-func isBinary(data []byte) bool {
-	if len(data) == 0 {
-		return false
-	}
-	// NULLバイトが含まれる場合はバイナリとみなす
-	if bytes.IndexByte(data, 0) != -1 {
-		return true
-	}
-	// http.DetectContentTypeによる判定
-	contentType := http.DetectContentType(data)
-	return !strings.HasPrefix(contentType, "text/") &&
-		contentType != "application/json" &&
-		contentType != "application/xml"
+// countingWriter は書き込んだバイト数を数えます。
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
 }
@@ -0,0 +1,129 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/kazuki-sk/codepack/internal/ignorer"
+	"github.com/kazuki-sk/codepack/internal/language"
+)
+
+// declineLarge は大容量ファイルを常に除外する LargeFileHandler です。
+type declineLarge struct{}
+
+func (declineLarge) ShouldInclude(context.Context, string, int64) (bool, error) {
+	return false, nil
+}
+
+// execute は opts の必須コンポーネントを補って fsys を処理し、出力と集計を返します。
+func execute(t *testing.T, fsys fs.FS, opts Options) (string, Stats) {
+	t.Helper()
+	if opts.Ignorer == nil {
+		opts.Ignorer = ignorer.NewIgnorer()
+	}
+	if opts.Mapper == nil {
+		m, err := language.NewMapper("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		opts.Mapper = m
+	}
+	if opts.LargeFileHandler == nil {
+		opts.LargeFileHandler = declineLarge{}
+	}
+
+	var out bytes.Buffer
+	p, err := NewProcessor(fsys, &out, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats, err := p.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	return out.String(), stats
+}
+
+func TestExecute(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.go":          {Data: []byte("package main\n")},
+		"tool/run.py":      {Data: []byte("print('hi')\n")},
+		"notes.unknownext": {Data: []byte("plain text\n")},
+		"debug.log":        {Data: []byte("ignored by pattern\n")},
+		"build/out.txt":    {Data: []byte("ignored by directory\n")},
+		"assets/logo.png":  {Data: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")},
+		"data.bin":         {Data: []byte("abc\x00\x01\x02def")},
+		"docs/日本語.md":      {Data: []byte("# 見出し\n")},
+	}
+	rules := ignorer.NewGitIgnoreMatcher(strings.NewReader("*.log\nbuild/\n"))
+	out, stats := execute(t, fsys, Options{Ignorer: ignorer.NewIgnorer(ignorer.Named("test", rules))})
+
+	// 除外ルール: ファイル名のパターンとディレクトリ（配下は走査しない）
+	for _, name := range []string{"debug.log", "build/out.txt"} {
+		if strings.Contains(out, "## File: "+name) {
+			t.Errorf("%s was not ignored", name)
+		}
+	}
+	if stats.IgnoredFiles != 1 || stats.IgnoredDirs != 1 {
+		t.Errorf("ignored = %d files, %d dirs, want 1, 1", stats.IgnoredFiles, stats.IgnoredDirs)
+	}
+	for _, s := range stats.Skipped {
+		if s.Reason != SkipIgnored || s.Detail != "test" {
+			t.Errorf("skip %+v, want reason %q from %q", s, SkipIgnored, "test")
+		}
+	}
+
+	// バイナリの判定: マジックナンバーと制御文字。内容は出力しない
+	if stats.Binary != 2 {
+		t.Errorf("Binary = %d, want 2", stats.Binary)
+	}
+	for _, want := range []string{
+		"## File: assets/logo.png\n\n(Binary file skipped)\n\n- Format: png\n",
+		"## File: data.bin\n\n(Binary file skipped)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+	if strings.Contains(out, "IHDR") || strings.Contains(out, "def") {
+		t.Error("binary content was written")
+	}
+
+	// 言語の判定: フェンスの言語（不明な拡張子は言語なし）
+	if stats.Files != 4 {
+		t.Errorf("Files = %d, want 4", stats.Files)
+	}
+	for _, want := range []string{
+		"## File: main.go\n\n```Go\npackage main\n",
+		"## File: tool/run.py\n\n```Python\n",
+		"## File: docs/日本語.md\n\n```Markdown\n# 見出し\n",
+		"## File: notes.unknownext\n\n```\nplain text\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q", want)
+		}
+	}
+}
+
+// 変換（アウトライン・ノートブック）に失敗したファイルは元の内容を出力し、行番号も付ける
+func TestExecuteConversionFailed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"broken.go":    {Data: []byte("package main\n\nfunc (\n")},
+		"broken.ipynb": {Data: []byte("{not json\n")},
+		"ok.go":        {Data: []byte("package main\n\nfunc f() {\n\treturn\n}\n")},
+	}
+	out, _ := execute(t, fsys, Options{Outline: true, LineNumbers: true})
+	for _, want := range []string{
+		"   1 | package main\n   2 | \n   3 | func (\n",
+		"   1 | {not json\n",
+		"package main\n\nfunc f() { ... }\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output does not contain %q:\n%s", want, out)
+		}
+	}
+}
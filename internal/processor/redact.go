@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/kazuki-sk/codepack/internal/secret"
+)
+
+// ErrSecretsFound は FailOnSecret の指定時に、秘密情報を置き換えたことを示すエラーです。
+var ErrSecretsFound = errors.New("secrets were found and redacted")
+
+// Redaction は秘密情報を置き換えた1か所の記録です。置き換えた値そのものは含みません。
+type Redaction struct {
+	Path string `json:"path"`
+	Line int    `json:"line"` // 内容の行番号（Diff の場合はパッチ内の行番号）
+	Rule string `json:"rule"`
+	Diff bool   `json:"diff,omitempty"` // 差分のパッチ内で検出した場合 true
+}
+
+// redactFilter は Secrets の規則で各行の秘密情報を置き換える lineFilter です。
+type redactFilter struct {
+	p    *Processor
+	r    *secret.Redactor
+	path string
+	diff bool
+	n    int
+	// within が nil でない場合、その範囲の行の置き換えのみを記録します（出力しない行は報告しない）。
+	within *lineRange
+}
+
+// redactor は name の内容の先頭から適用する redactFilter を返します（Secrets が nil の場合は nil）。
+// 秘密鍵のブロックの途中から始まる部分を見落とさないよう、ファイルの一部を出力する場合も全ての行に適用します。
+func (p *Processor) redactor(name string) *redactFilter {
+	if p.opts.Secrets == nil {
+		return nil
+	}
+	return p.newRedactFilter(name, false)
+}
+
+func (p *Processor) newRedactFilter(path string, diff bool) *redactFilter {
+	return &redactFilter{p: p, r: p.opts.Secrets.NewRedactor(), path: path, diff: diff}
+}
+
+func (f *redactFilter) filterLine(line []byte) []byte {
+	f.n++
+	out, rules := f.r.Redact(line)
+	if f.within != nil && !f.within.contains(f.n) {
+		return out
+	}
+	for _, rule := range rules {
+		f.p.redacted(Redaction{Path: f.path, Line: f.n, Rule: rule, Diff: f.diff})
+	}
+	return out
+}
+
+// redacted は置き換えの記録と集計を行います。
+func (p *Processor) redacted(r Redaction) {
+	p.stats.Redacted++
+	p.stats.Redactions = append(p.stats.Redactions, r)
+	p.log.Debug("redacted secret", "path", r.Path, "line", r.Line, "rule", r.Rule)
+}
+
+// redactPatch は差分のパッチ内の秘密情報を置き換えます（Secrets が nil の場合はそのまま返します）。
+// ハンクは秘密鍵のブロックの途中から始まることがあるため、ハンクごとにブロックの内側かを判定します。
+func (p *Processor) redactPatch(path, patch string) string {
+	if p.opts.Secrets == nil {
+		return patch
+	}
+	f := p.newRedactFilter(path, true)
+	lines := bytes.SplitAfter([]byte(patch), []byte("\n"))
+	var b bytes.Buffer
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		if !bytes.HasPrefix(line, []byte("@@")) {
+			b.Write(f.filterLine(line))
+			continue
+		}
+		// ハンクの見出しはそのまま出力し、ブロックの内側から始まる場合は置き換えを1行出力する
+		f.n++
+		b.Write(line)
+		hunk := hunkLines(lines[i+1:])
+		inKey := secret.InKeyBlock(hunk)
+		f.r.SetInKeyBlock(inKey)
+		if inKey {
+			fmt.Fprintf(&b, " %s\n", secret.Redacted(secret.PrivateKeyRule))
+			p.redacted(Redaction{Path: path, Line: f.n, Rule: secret.PrivateKeyRule, Diff: true})
+		}
+	}
+	return b.String()
+}
+
+// hunkLines は次のハンク（またはファイル）の見出しまでの行を、先頭の +・-・空白を除いて返します。
+func hunkLines(lines [][]byte) [][]byte {
+	var hunk [][]byte
+	for _, line := range lines {
+		if bytes.HasPrefix(line, []byte("@@")) || bytes.HasPrefix(line, []byte("diff ")) {
+			break
+		}
+		if len(line) > 0 {
+			hunk = append(hunk, line[1:])
+		}
+	}
+	return hunk
+}
+
+// secretsError は FailOnSecret の指定時に、置き換えがあれば ErrSecretsFound を返します。
+func (p *Processor) secretsError() error {
+	if !p.opts.FailOnSecret || p.stats.Redacted == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d occurrence(s)", ErrSecretsFound, p.stats.Redacted)
+}
@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// Replacement は内容（と見出しのパス）を書き換える正規表現の規則です。
+type Replacement struct {
+	// Pattern は行末の改行（\n または \r\n）を除いた1行ごとに照合するため、$ は行末に一致します。
+	// 改行は置き換え後に付け直します。複数行にまたがる一致は扱いません。
+	Pattern *regexp.Regexp
+	// Replacement は置き換え後の文字列です。$1 や ${name} でサブマッチを参照できます。
+	Replacement string
+	// Glob は対象のファイルです（SizeRule.Pattern と同じ形式）。空の場合は全ファイルです。
+	Glob string
+	// Paths が true の場合、見出しに表示するパスにも適用します。
+	Paths bool
+}
+
+// appliesTo は規則がファイル name の内容に適用されるかを返します。
+func (r Replacement) appliesTo(name string) bool {
+	return r.Glob == "" || matchPattern(r.Glob, name)
+}
+
+// replaceFilter は name に適用される規則を各行に順に適用する lineFilter です。
+// 行単位で照合するため、copyCancellable のバッファの境界で一致を取りこぼすことはありません。
+type replaceFilter struct {
+	rules []Replacement
+}
+
+func (f *replaceFilter) filterLine(line []byte) []byte {
+	body := bytes.TrimSuffix(line, []byte("\n"))
+	body = bytes.TrimSuffix(body, []byte("\r"))
+	eol := line[len(body):]
+	for _, r := range f.rules {
+		body = r.Pattern.ReplaceAll(body, []byte(r.Replacement))
+	}
+	return append(body, eol...)
+}
+
+// replacementsFor は name の内容に適用する規則を返します。
+func (p *Processor) replacementsFor(name string) []Replacement {
+	var rules []Replacement
+	for _, r := range p.opts.Replacements {
+		if r.appliesTo(name) {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// displayPath は見出しに表示する name を返します（Paths が true の規則のみを適用します）。
+func (p *Processor) displayPath(name string) string {
+	for _, r := range p.opts.Replacements {
+		if r.Paths && r.appliesTo(name) {
+			name = r.Pattern.ReplaceAllString(name, r.Replacement)
+		}
+	}
+	return name
+}
+
+// replacePatch は差分のパッチに name の内容と同じ規則を、内容と同様に1行ずつ適用します。
+func (p *Processor) replacePatch(name, patch string) string {
+	rules := p.replacementsFor(name)
+	if len(rules) == 0 {
+		return patch
+	}
+	f := &replaceFilter{rules: rules}
+	var b bytes.Buffer
+	for _, line := range bytes.SplitAfter([]byte(patch), []byte("\n")) {
+		b.Write(f.filterLine(line))
+	}
+	return b.String()
+}
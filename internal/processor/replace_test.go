@@ -0,0 +1,28 @@
+package processor
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestReplaceFilterLineEnding(t *testing.T) {
+	f := &replaceFilter{rules: []Replacement{
+		{Pattern: regexp.MustCompile(`internal\.corp"$`), Replacement: `example.com"`},
+		{Pattern: regexp.MustCompile(`^(\s*)secret`), Replacement: "${1}[scrubbed]"},
+	}}
+	tests := []struct {
+		line, want string
+	}{
+		{"host = \"db.internal.corp\"\n", "host = \"db.example.com\"\n"},
+		{"host = \"db.internal.corp\"\r\n", "host = \"db.example.com\"\r\n"},
+		{"host = \"db.internal.corp\"", "host = \"db.example.com\""},
+		{"url = \"internal.corp\"/path\n", "url = \"internal.corp\"/path\n"},
+		{"  secret = 1\n", "  [scrubbed] = 1\n"},
+		{"\n", "\n"},
+	}
+	for _, tt := range tests {
+		if got := string(f.filterLine([]byte(tt.line))); got != tt.want {
+			t.Errorf("filterLine(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
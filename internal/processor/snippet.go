@@ -0,0 +1,187 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Snippet はファイルの一部（行の範囲）を1エントリとして出力する指定です。
+// 出力する各行には、ファイル内の実際の行番号が付きます。
+type Snippet struct {
+	Path  string // fsys のルートからの相対パス（スラッシュ区切り）
+	Start int    // 最初の行（1 始まり）
+	End   int    // 最後の行（0 の場合はファイルの末尾まで）
+	// Symbol が空でない場合、Start / End の代わりにこの名前の宣言（"run"、"Type.Method" など）の範囲を出力します。
+	Symbol string
+	Note   string // 見出しに添える説明（"caller of pkg.Func" など。任意）
+}
+
+// snippetRange は "START"、"START-END"、"START-" の形式の行の範囲です。
+var snippetRange = regexp.MustCompile(`^(\d+)(-(\d*))?$`)
+
+// ParseSnippet は "path:120-180"（行の範囲）または "path#run"（宣言の名前）の形式の指定を解析します。
+// "path:120" は1行、"path:120-" は末尾までを表します。
+// パスが # を含む場合（"src/C#/a.cs:10-20" など）のため、行の範囲の形式を先に判定し、
+// # 以降に / や : を含む場合は宣言の名前とみなしません。
+func ParseSnippet(spec string) (Snippet, error) {
+	if i := strings.LastIndex(spec, ":"); i > 0 {
+		if m := snippetRange.FindStringSubmatch(spec[i+1:]); m != nil {
+			s := Snippet{Path: path.Clean(spec[:i])}
+			s.Start, _ = strconv.Atoi(m[1])
+			switch {
+			case m[2] == "":
+				s.End = s.Start
+			case m[3] != "":
+				s.End, _ = strconv.Atoi(m[3])
+			}
+			if s.Start < 1 || (s.End != 0 && s.End < s.Start) {
+				return Snippet{}, fmt.Errorf("invalid line range in snippet %q", spec)
+			}
+			return s, nil
+		}
+	}
+	if i := strings.LastIndex(spec, "#"); i > 0 && i < len(spec)-1 && !strings.ContainsAny(spec[i+1:], "/:") {
+		return Snippet{Path: path.Clean(spec[:i]), Symbol: spec[i+1:]}, nil
+	}
+	if i := strings.LastIndex(spec, ":"); i > 0 {
+		return Snippet{}, fmt.Errorf("invalid line range in snippet %q (want START, START-END or START-)", spec)
+	}
+	return Snippet{}, fmt.Errorf("invalid snippet %q (want PATH:START-END or PATH#SYMBOL)", spec)
+}
+
+// ReadSnippets は1行に1つの指定を並べたリストを読み込みます。空行と # で始まる行は無視します。
+func ReadSnippets(r io.Reader) ([]Snippet, error) {
+	var snippets []Snippet
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, err := ParseSnippet(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		snippets = append(snippets, s)
+	}
+	return snippets, sc.Err()
+}
+
+// writeSnippets は Options.Snippets を指定順に出力します。
+func (p *Processor) writeSnippets(ctx context.Context) error {
+	for _, s := range p.opts.Snippets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := p.processSnippet(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processSnippet はファイルの指定範囲の行を読み込み、出力します。
+// 文字コードの変換と行単位の変換（コメントの除去など）はファイル全体の場合と同様に適用します。
+// 範囲の指定は利用者によるものであるため、大容量判定は行いません。
+func (p *Processor) processSnippet(ctx context.Context, s Snippet) error {
+	file, err := p.fsys.Open(s.Path)
+	if err != nil {
+		p.skip(s.Path, false, SkipUnreadable, err.Error())
+		return nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		p.skip(s.Path, false, SkipUnreadable, err.Error())
+		return nil
+	}
+
+	src := &peekSource{r: file}
+	sample, err := src.peek(encodingSampleSize)
+	if err != nil {
+		p.skip(s.Path, false, SkipUnreadable, err.Error())
+		return nil
+	}
+	if binary, _ := p.classify(s.Path, sample); binary {
+		p.skip(s.Path, false, SkipUnreadable, "binary file has no lines")
+		return nil
+	}
+	enc := p.detectEncoding(s.Path, sample, info.Size())
+
+	// 宣言の名前で指定された場合は、内容全体から範囲を求める
+	content := enc.reader(src.reader())
+	if s.Symbol != "" {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			p.skip(s.Path, false, SkipUnreadable, err.Error())
+			return nil
+		}
+		start, end, ok := p.symbolLines(s.Path, data, s.Symbol)
+		if !ok {
+			p.skip(s.Path, false, SkipUnreadable, fmt.Sprintf("symbol %q not found", s.Symbol))
+			return nil
+		}
+		s.Start, s.End = start, end
+		if s.Note == "" {
+			s.Note = s.Symbol
+		}
+		content = bytes.NewReader(data)
+	}
+
+	entry := Entry{
+		Path:      s.Path,
+		Language:  p.opts.Mapper.GetLanguage(s.Path),
+		Size:      info.Size(),
+		Encoding:  enc.name,
+		StartLine: s.Start,
+		EndLine:   s.End,
+		Note:      s.Note,
+	}
+	if entry.StartLine < 1 {
+		entry.StartLine = 1
+	}
+
+	// 範囲の選択と行番号は元の行で数えるため、他の変換（コメントの除去など）は番号付けの内側で適用する。
+	// 秘密情報の置き換えは範囲の前の行も含めて先頭から行う（範囲が秘密鍵のブロックの途中から始まる場合のため）
+	rng := &lineRange{start: entry.StartLine, end: s.End}
+	if r := p.redactor(s.Path); r != nil {
+		r.within = rng
+		rng.pre = r
+	}
+	filters := append([]lineFilter{rng}, p.transformFilters(s.Path, entry)...)
+	entry.LineNumbers = true
+	numbered := p.newLineNumberer(numberWidth(s.End), filters...)
+	return p.writeEntry(ctx, entry, newLineFilterReader(content, numbered))
+}
+
+// lineRange は start 行目から end 行目まで（end が 0 の場合は末尾まで）の行のみを通します。
+// pre は範囲の外の行も含めて全ての行に、範囲の選択より先に適用します。
+type lineRange struct {
+	start, end int
+	pre        lineFilter
+	n          int
+}
+
+func (r *lineRange) filterLine(line []byte) []byte {
+	r.n++
+	if r.pre != nil {
+		line = r.pre.filterLine(line)
+	}
+	if !r.contains(r.n) {
+		return nil
+	}
+	return line
+}
+
+// contains は n 行目が範囲内かを返します。
+func (r *lineRange) contains(n int) bool {
+	return n >= r.start && (r.end == 0 || n <= r.end)
+}
@@ -0,0 +1,215 @@
+package processor
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// symbolLines は内容 data のうち、symbol の宣言（直前のコメントを含む）の行の範囲を返します。
+// Go は構文解析により、"Func"、"Type"、"Type.Method" の形式で探します。
+// それ以外の言語は宣言のキーワードを手がかりに探し、括弧の対応（Python はインデント）で終わりを求めます。
+func (p *Processor) symbolLines(name string, data []byte, symbol string) (int, int, bool) {
+	if isGoSource(name) {
+		return goSymbolLines(name, data, symbol)
+	}
+	return heuristicSymbolLines(data, symbol, commentSyntaxFor(p.opts.Mapper.Languages(name)))
+}
+
+// goSymbolLines は Go のトップレベルの宣言の範囲を返します。
+func goSymbolLines(name string, data []byte, symbol string) (int, int, bool) {
+	fset := token.NewFileSet()
+	// 構文エラーがあっても、解析できた部分の宣言から探す
+	f, _ := parser.ParseFile(fset, name, data, parser.ParseComments)
+	if f == nil {
+		return 0, 0, false
+	}
+	symbol = strings.NewReplacer("(", "", ")", "", "*", "").Replace(symbol)
+
+	lines := func(start, end token.Pos) (int, int, bool) {
+		return fset.Position(start).Line, fset.Position(end).Line, true
+	}
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if goFuncName(d) != symbol {
+				continue
+			}
+			if d.Doc != nil {
+				return lines(d.Doc.Pos(), d.End())
+			}
+			return lines(d.Pos(), d.End())
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				var (
+					names []*ast.Ident
+					doc   *ast.CommentGroup
+				)
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names, doc = []*ast.Ident{s.Name}, s.Doc
+				case *ast.ValueSpec:
+					names, doc = s.Names, s.Doc
+				}
+				for _, id := range names {
+					if id.Name != symbol {
+						continue
+					}
+					// 括弧でまとめていない宣言は type / var / const のキーワードから含める
+					var node ast.Node = spec
+					if !d.Lparen.IsValid() {
+						node, doc = d, d.Doc
+					}
+					if doc != nil {
+						return lines(doc.Pos(), node.End())
+					}
+					return lines(node.Pos(), node.End())
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// goFuncName は関数名、またはメソッドの場合は "Type.Method" を返します。
+func goFuncName(d *ast.FuncDecl) string {
+	if d.Recv == nil || len(d.Recv.List) == 0 {
+		return d.Name.Name
+	}
+	t := d.Recv.List[0].Type
+	for {
+		switch x := t.(type) {
+		case *ast.StarExpr:
+			t = x.X
+		case *ast.IndexExpr:
+			t = x.X
+		case *ast.IndexListExpr:
+			t = x.X
+		case *ast.Ident:
+			return x.Name + "." + d.Name.Name
+		default:
+			return d.Name.Name
+		}
+	}
+}
+
+// declKeywords は宣言の名前の直前に置かれるキーワードです。
+const declKeywords = `def|class|function|func|fn|interface|struct|enum|union|trait|impl|type|module|namespace|record|object|sub|proc|macro_rules!`
+
+// heuristicSymbolLines は Go 以外の言語の宣言の範囲を推定します。
+// "Class.method" の形式の場合は、クラスの宣言より後にあるメソッドを探します。
+func heuristicSymbolLines(data []byte, symbol string, syn *commentSyntax) (int, int, bool) {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	from := 0
+	parts := strings.Split(symbol, ".")
+	for i, part := range parts {
+		start := findDeclLine(lines, from, part)
+		if start < 0 {
+			return 0, 0, false
+		}
+		if i < len(parts)-1 {
+			from = start + 1
+			continue
+		}
+		end := declEnd(lines, start, syn)
+		return declCommentStart(lines, start) + 1, end + 1, true
+	}
+	return 0, 0, false
+}
+
+// findDeclLine は from 行目以降で name を宣言している行（0 始まり）を返します（見つからない場合は -1）。
+// キーワードによる宣言（def name、class name など）を優先し、なければ name( で始まる宣言らしい行
+// （Java や C の関数・メソッド。文末の ; や代入を含まないもの）を探します。
+func findDeclLine(lines [][]byte, from int, name string) int {
+	q := regexp.QuoteMeta(name)
+	keyword := regexp.MustCompile(`\b(?:` + declKeywords + `)\s+` + q + `\b`)
+	assigned := regexp.MustCompile(`\b(?:const|let|var)\s+` + q + `\s*=\s*(?:async\s*)?(?:function\b|\(|[\w$]+\s*=>)`)
+	signature := regexp.MustCompile(`^\s*[\w$<>\[\]*&:,\s]*\b` + q + `\s*\([^;=]*$`)
+	for _, re := range []*regexp.Regexp{keyword, assigned, signature} {
+		for i := from; i < len(lines); i++ {
+			line := bytes.TrimRight(lines[i], "\r\n")
+			if re.Match(line) && !bytes.HasPrefix(bytes.TrimSpace(line), []byte("return")) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// declEnd は start 行目から始まる宣言の最後の行を返します。
+// 波括弧の対応を取り（文字列・コメント内は数えません）、{ の前に ; があれば宣言のみとみなします。
+// 波括弧を持たない言語（Python など、行コメントが # のもの）はインデントで判断します。
+func declEnd(lines [][]byte, start int, syn *commentSyntax) int {
+	if syn == nil || syn.blockStart == "" {
+		return indentEnd(lines, start)
+	}
+	o := braceOutliner{syn: syn}
+	src := bytes.Join(lines[start:], nil)
+	depth, opened, line := 0, false, start
+	for i := 0; i < len(src); {
+		if n := o.skipLiteral(src[i:]); n > 0 {
+			line += bytes.Count(src[i:i+n], []byte("\n"))
+			i += n
+			continue
+		}
+		switch src[i] {
+		case '\n':
+			line++
+		case '{':
+			depth++
+			opened = true
+		case '}':
+			depth--
+			if opened && depth == 0 {
+				return line
+			}
+		case ';':
+			if !opened {
+				return line
+			}
+		}
+		i++
+	}
+	return len(lines) - 1
+}
+
+// indentEnd は start 行目より深くインデントされた後続の行（空行を挟んでもよい）の最後の行を返します。
+func indentEnd(lines [][]byte, start int) int {
+	base := len(indentOf(string(lines[start])))
+	end := start
+	for i := start + 1; i < len(lines); i++ {
+		if isBlank(lines[i]) {
+			continue
+		}
+		if len(indentOf(string(lines[i]))) <= base {
+			break
+		}
+		end = i
+	}
+	return end
+}
+
+// declCommentStart は start 行目の直前に続くコメント・デコレーター・アノテーションの最初の行を返します。
+func declCommentStart(lines [][]byte, start int) int {
+	for start > 0 {
+		prev := bytes.TrimSpace(lines[start-1])
+		if len(prev) == 0 {
+			break
+		}
+		isComment := false
+		for _, prefix := range []string{"//", "#", "/*", "*", "@", "--", ";"} {
+			if bytes.HasPrefix(prev, []byte(prefix)) {
+				isComment = true
+				break
+			}
+		}
+		if !isComment {
+			break
+		}
+		start--
+	}
+	return start
+}
@@ -0,0 +1,36 @@
+package processor
+
+import "testing"
+
+func TestParseSnippet(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Snippet
+	}{
+		{"main.go:10-20", Snippet{Path: "main.go", Start: 10, End: 20}},
+		{"main.go:10", Snippet{Path: "main.go", Start: 10, End: 10}},
+		{"./main.go:10-", Snippet{Path: "main.go", Start: 10}},
+		{"cmd/main.go#run", Snippet{Path: "cmd/main.go", Symbol: "run"}},
+		{"a.go#Type.Method", Snippet{Path: "a.go", Symbol: "Type.Method"}},
+		// パスに # を含む場合
+		{"src/C#/a.cs:10-20", Snippet{Path: "src/C#/a.cs", Start: 10, End: 20}},
+		{"src/C#/a.cs#Main", Snippet{Path: "src/C#/a.cs", Symbol: "Main"}},
+		{"notes#1.md:3", Snippet{Path: "notes#1.md", Start: 3, End: 3}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSnippet(tt.spec)
+		if err != nil {
+			t.Errorf("ParseSnippet(%q): %v", tt.spec, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSnippet(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"main.go", "main.go:", "main.go:x", "main.go:20-10", "main.go:0", "src/C#/a.cs", "main.go#"} {
+		if s, err := ParseSnippet(spec); err == nil {
+			t.Errorf("ParseSnippet(%q) = %+v, want an error", spec, s)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package processor
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+// quoteSyntax は文字列リテラルの区切りです。
+type quoteSyntax struct {
+	delim     string
+	escapes   bool // バックスラッシュによるエスケープがあるか
+	multiline bool // 複数行にまたがるか（false の場合は行末で文字列を終了したものとみなす）
+}
+
+// commentSyntax は言語系統ごとのコメントと文字列リテラルの構文です。
+type commentSyntax struct {
+	line       []string // 行コメントの開始
+	lineAtWord bool     // 行コメントは行頭または空白の直後のみ（シェルの $# などと区別する）
+	blockStart string   // ブロックコメントの開始（ない場合は空）
+	blockEnd   string
+	quotes     []quoteSyntax // 長い区切りを先に並べる
+	charQuote  bool          // ' を文字リテラルとして扱う（'a' のように短い場合のみ。Rust のライフタイム等と区別する）
+
+	docLine     []string // ドキュメントコメントとして残す行コメントの開始
+	docBlock    string   // ドキュメントコメントとして残すブロックコメントの開始
+	docTopLevel bool     // 行頭から始まる行コメントをドキュメントコメントとする（Go）
+	shebang     bool     // 1行目の #! を残す
+}
+
+var (
+	dq     = quoteSyntax{delim: `"`, escapes: true}
+	sq     = quoteSyntax{delim: `'`, escapes: true}
+	bqRaw  = quoteSyntax{delim: "`", multiline: true}
+	bqTmpl = quoteSyntax{delim: "`", escapes: true, multiline: true}
+
+	cLike = &commentSyntax{
+		line: []string{"//"}, blockStart: "/*", blockEnd: "*/",
+		quotes: []quoteSyntax{dq}, charQuote: true,
+		docLine: []string{"///", "//!"}, docBlock: "/**",
+	}
+	goSyntax = &commentSyntax{
+		line: []string{"//"}, blockStart: "/*", blockEnd: "*/",
+		quotes: []quoteSyntax{dq, bqRaw}, charQuote: true,
+		docTopLevel: true,
+	}
+	jsLike = &commentSyntax{
+		line: []string{"//"}, blockStart: "/*", blockEnd: "*/",
+		quotes:   []quoteSyntax{dq, sq, bqTmpl},
+		docBlock: "/**",
+	}
+	cssLike = &commentSyntax{
+		blockStart: "/*", blockEnd: "*/",
+		quotes: []quoteSyntax{dq, sq},
+	}
+	scssLike = &commentSyntax{
+		line: []string{"//"}, lineAtWord: true, blockStart: "/*", blockEnd: "*/",
+		quotes: []quoteSyntax{dq, sq},
+	}
+	phpLike = &commentSyntax{
+		line: []string{"//", "#"}, blockStart: "/*", blockEnd: "*/",
+		quotes:   []quoteSyntax{dq, sq},
+		docBlock: "/**",
+	}
+	hashLike = &commentSyntax{
+		line: []string{"#"}, lineAtWord: true,
+		quotes: []quoteSyntax{dq, sq}, shebang: true,
+	}
+	pythonLike = &commentSyntax{
+		line: []string{"#"}, lineAtWord: true,
+		quotes: []quoteSyntax{
+			{delim: `"""`, escapes: true, multiline: true},
+			{delim: `'''`, escapes: true, multiline: true},
+			dq, sq,
+		},
+		shebang: true,
+	}
+	sqlLike = &commentSyntax{
+		line: []string{"--"}, blockStart: "/*", blockEnd: "*/",
+		quotes: []quoteSyntax{{delim: `'`, multiline: true}, {delim: `"`}},
+	}
+	xmlLike = &commentSyntax{
+		blockStart: "<!--", blockEnd: "-->",
+	}
+	lispLike = &commentSyntax{
+		line: []string{";"}, blockStart: "#|", blockEnd: "|#",
+		quotes: []quoteSyntax{{delim: `"`, escapes: true, multiline: true}},
+	}
+	clojureLike = &commentSyntax{
+		line:   []string{";"},
+		quotes: []quoteSyntax{{delim: `"`, escapes: true, multiline: true}},
+	}
+)
+
+// commentSyntaxByLanguage は language.Mapper の言語名と構文の対応です。
+var commentSyntaxByLanguage = map[string]*commentSyntax{
+	"Go": goSyntax,
+
+	"C": cLike, "C++": cLike, "C#": cLike, "Objective-C": cLike, "Objective-C++": cLike,
+	"Java": cLike, "Kotlin": cLike, "Scala": cLike, "Groovy": cLike, "Gradle": cLike,
+	"Swift": cLike, "Rust": cLike, "RenderScript": cLike, "Dart": cLike, "Zig": cLike,
+	"Cuda": cLike, "GLSL": cLike, "Solidity": cLike, "Protocol Buffer": cLike,
+	"JSON with Comments": cLike,
+
+	"JavaScript": jsLike, "TypeScript": jsLike, "TSX": jsLike,
+
+	"CSS": cssLike, "SCSS": scssLike, "Less": scssLike,
+	"PHP": phpLike, "Hack": phpLike,
+
+	"Shell": hashLike, "Ruby": hashLike, "Perl": hashLike, "R": hashLike,
+	"YAML": hashLike, "TOML": hashLike, "Makefile": hashLike, "Dockerfile": hashLike,
+	"PowerShell": hashLike, "Elixir": hashLike, "Nim": hashLike, "Crystal": hashLike,
+	"CoffeeScript": hashLike, "Julia": hashLike, "Tcl": hashLike, "HCL": hashLike,
+	"GraphQL": hashLike, "Nix": hashLike,
+	"Python": pythonLike, "Starlark": pythonLike,
+
+	"SQL": sqlLike, "TSQL": sqlLike, "PLSQL": sqlLike, "PLpgSQL": sqlLike, "SQLPL": sqlLike,
+
+	"HTML": xmlLike, "XML": xmlLike, "SVG": xmlLike, "XSLT": xmlLike, "Vue": xmlLike, "Svelte": xmlLike,
+
+	"Emacs Lisp": lispLike, "Common Lisp": lispLike, "Scheme": lispLike, "Racket": lispLike,
+	"Clojure": clojureLike,
+}
+
+// commentSyntaxFor は言語名の候補のうち、構文が分かる最初のものを返します（不明な場合は nil）。
+func commentSyntaxFor(langs []string) *commentSyntax {
+	for _, l := range langs {
+		if s, ok := commentSyntaxByLanguage[l]; ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// commentStripper は文字列リテラルを考慮しながら行コメント・ブロックコメントを取り除きます。
+// コメントのみの行は行ごと削除し、コードの後ろのコメントは直前の空白とともに削除します。
+type commentStripper struct {
+	syn      *commentSyntax
+	keepDocs bool
+
+	// 行をまたぐ状態
+	lineNo    int
+	inBlock   bool         // ブロックコメントの途中
+	keepBlock bool         // 途中のブロックコメントを残すか（ドキュメントコメント）
+	quote     *quoteSyntax // 複数行の文字列リテラルの途中（文字列外は nil）
+}
+
+func newCommentStripper(syn *commentSyntax, keepDocs bool) *commentStripper {
+	return &commentStripper{syn: syn, keepDocs: keepDocs}
+}
+
+func (s *commentStripper) filterLine(line []byte) []byte {
+	s.lineNo++
+	body := bytes.TrimRight(line, "\r\n")
+	eol := line[len(body):]
+
+	if len(body) == 0 && s.inBlock && !s.keepBlock {
+		return nil // ブロックコメント内の空行
+	}
+
+	out := make([]byte, 0, len(line))
+	removed := false // コメントを取り除いたか
+	removedEnd := -1 // 最後にコメントを取り除いた位置（out の長さ）
+
+	for i := 0; i < len(body); {
+		// ブロックコメントの途中
+		if s.inBlock {
+			end := bytes.Index(body[i:], []byte(s.syn.blockEnd))
+			if end < 0 {
+				if s.keepBlock {
+					out = append(out, body[i:]...)
+				} else {
+					removed, removedEnd = true, len(out)
+				}
+				i = len(body)
+				break
+			}
+			end += i + len(s.syn.blockEnd)
+			if s.keepBlock {
+				out = append(out, body[i:end]...)
+			} else {
+				removed, removedEnd = true, len(out)
+			}
+			s.inBlock = false
+			i = end
+			continue
+		}
+
+		// 文字列リテラルの途中
+		if s.quote != nil {
+			j := i
+			closed := false
+			for j < len(body) {
+				if s.quote.escapes && body[j] == '\\' {
+					j += 2
+					continue
+				}
+				if bytes.HasPrefix(body[j:], []byte(s.quote.delim)) {
+					j += len(s.quote.delim)
+					closed = true
+					break
+				}
+				j++
+			}
+			if j > len(body) {
+				j = len(body)
+			}
+			out = append(out, body[i:j]...)
+			i = j
+			if closed || !s.quote.multiline {
+				s.quote = nil
+			}
+			continue
+		}
+
+		rest := body[i:]
+
+		// ブロックコメントの開始
+		if s.syn.blockStart != "" && bytes.HasPrefix(rest, []byte(s.syn.blockStart)) {
+			s.inBlock = true
+			s.keepBlock = s.keepDocs && s.syn.docBlock != "" && bytes.HasPrefix(rest, []byte(s.syn.docBlock))
+			if s.keepBlock {
+				out = append(out, s.syn.blockStart...)
+			} else {
+				removed, removedEnd = true, len(out)
+			}
+			i += len(s.syn.blockStart)
+			continue
+		}
+
+		// 行コメント
+		if s.isLineComment(body, i) {
+			if s.keepLineComment(rest, i) {
+				out = append(out, rest...)
+			} else {
+				removed, removedEnd = true, len(out)
+			}
+			break
+		}
+
+		// 文字列リテラルの開始
+		if q := s.quoteAt(rest); q != nil {
+			s.quote = q
+			out = append(out, q.delim...)
+			i += len(q.delim)
+			continue
+		}
+		if s.syn.charQuote && rest[0] == '\'' {
+			if n := charLiteralLen(rest); n > 0 {
+				out = append(out, rest[:n]...)
+				i += n
+				continue
+			}
+		}
+
+		out = append(out, body[i])
+		i++
+	}
+
+	// 文字列は行末で終了（複数行の文字列のみ継続）
+	if s.quote != nil && !s.quote.multiline {
+		s.quote = nil
+	}
+
+	if removed {
+		if isBlank(out) {
+			return nil // コメントのみの行は行ごと削除
+		}
+		if removedEnd == len(out) {
+			// 行末のコメント（行コメント・ブロックコメント）は直前の空白とともに削除する
+			out = bytes.TrimRight(out, " \t")
+		}
+	}
+	return append(out, eol...)
+}
+
+// isLineComment は body[i] から行コメントが始まるかを返します。
+func (s *commentStripper) isLineComment(body []byte, i int) bool {
+	for _, l := range s.syn.line {
+		if !bytes.HasPrefix(body[i:], []byte(l)) {
+			continue
+		}
+		if s.syn.lineAtWord && i > 0 && body[i-1] != ' ' && body[i-1] != '\t' {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// keepLineComment は行コメントを残すか（ドキュメントコメント・shebang）を返します。
+func (s *commentStripper) keepLineComment(comment []byte, col int) bool {
+	if s.syn.shebang && s.lineNo == 1 && col == 0 && bytes.HasPrefix(comment, []byte("#!")) {
+		return true
+	}
+	if !s.keepDocs {
+		return false
+	}
+	if s.syn.docTopLevel && col == 0 {
+		return true
+	}
+	for _, d := range s.syn.docLine {
+		if bytes.HasPrefix(comment, []byte(d)) {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteAt は rest の先頭から始まる文字列リテラルの区切りを返します（ない場合は nil）。
+func (s *commentStripper) quoteAt(rest []byte) *quoteSyntax {
+	for i := range s.syn.quotes {
+		if bytes.HasPrefix(rest, []byte(s.syn.quotes[i].delim)) {
+			return &s.syn.quotes[i]
+		}
+	}
+	return nil
+}
+
+// charLiteralLen は 'a' や '\n' のような文字リテラルの長さを返します（文字リテラルでない場合は 0）。
+func charLiteralLen(rest []byte) int {
+	j := 1
+	if j < len(rest) && rest[j] == '\\' {
+		// '\n' '\'' '\x41' '\u{1F600}' などのエスケープ（エスケープされた1文字の後から終端を探す）
+		if j+2 >= len(rest) {
+			return 0
+		}
+		end := bytes.IndexByte(rest[j+2:], '\'')
+		if end < 0 || end > 10 {
+			return 0
+		}
+		return j + 2 + end + 1
+	}
+	_, size := utf8.DecodeRune(rest[j:])
+	j += size
+	if size > 0 && j < len(rest) && rest[j] == '\'' {
+		return j + 1
+	}
+	return 0
+}
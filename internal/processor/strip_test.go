@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"strings"
+	"testing"
+)
+
+// strip は src の各行に commentStripper を適用した結果を返します。
+func strip(syn *commentSyntax, keepDocs bool, src string) string {
+	s := newCommentStripper(syn, keepDocs)
+	var b strings.Builder
+	for _, line := range strings.SplitAfter(src, "\n") {
+		if line != "" {
+			b.Write(s.filterLine([]byte(line)))
+		}
+	}
+	return b.String()
+}
+
+func TestCommentStripper(t *testing.T) {
+	tests := []struct {
+		name     string
+		syn      *commentSyntax
+		keepDocs bool
+		src      string
+		want     string
+	}{
+		// 文字列リテラル内の // や # はコメントではない
+		{
+			name: "go url in string",
+			syn:  goSyntax,
+			src:  "url := \"http://x\" // endpoint\nraw := `//not a comment`\n\t// comment only\nr := '/'\n",
+			want: "url := \"http://x\"\nraw := `//not a comment`\nr := '/'\n",
+		},
+		{
+			name: "js url in strings",
+			syn:  jsLike,
+			src:  "const a = 'http://x'; // single\nconst b = \"http://y\";\nconst c = `${a}//${b}`; /* tail */\n",
+			want: "const a = 'http://x';\nconst b = \"http://y\";\nconst c = `${a}//${b}`;\n",
+		},
+		{
+			name: "js escaped quote",
+			syn:  jsLike,
+			src:  "s = \"a\\\"//b\" // c\n",
+			want: "s = \"a\\\"//b\"\n",
+		},
+		{
+			name: "shell hash in strings",
+			syn:  hashLike,
+			src:  "#!/bin/sh\n# comment\necho '#' \"#\" # trailing\nn=$#\nurl=http://x#frag\n",
+			want: "#!/bin/sh\necho '#' \"#\"\nn=$#\nurl=http://x#frag\n",
+		},
+		{
+			name: "python hash in strings",
+			syn:  pythonLike,
+			src:  "c = '#'  # hash\nd = \"#\"\ndoc = \"\"\"\n# not a comment\n\"\"\"\n# comment\n",
+			want: "c = '#'\nd = \"#\"\ndoc = \"\"\"\n# not a comment\n\"\"\"\n",
+		},
+		// 複数行にまたがるブロックコメント
+		{
+			name: "c block spanning lines",
+			syn:  cLike,
+			src:  "int a; /* start\n   middle\n\n   end */ int b;\n/* whole\n line */\nint c; // tail\nchar *s = \"/* not */\";\n",
+			want: "int a;\n int b;\nint c;\nchar *s = \"/* not */\";\n",
+		},
+		{
+			name: "sql",
+			syn:  sqlLike,
+			src:  "SELECT '--x' -- comment\nFROM t; /* block */\n",
+			want: "SELECT '--x'\nFROM t;\n",
+		},
+		{
+			name: "xml",
+			syn:  xmlLike,
+			src:  "<a>\n<!-- one\ntwo -->\n<b/> <!-- tail -->\n",
+			want: "<a>\n<b/>\n",
+		},
+		// ドキュメントコメントは残す
+		{
+			name:     "go doc comments",
+			syn:      goSyntax,
+			keepDocs: true,
+			src:      "// Package p does things.\npackage p\n\n// F is documented.\nfunc F() {\n\t// inner comment\n\treturn // tail\n}\n",
+			want:     "// Package p does things.\npackage p\n\n// F is documented.\nfunc F() {\n\treturn\n}\n",
+		},
+		{
+			name:     "js doc block",
+			syn:      jsLike,
+			keepDocs: true,
+			src:      "/**\n * Adds.\n */\nfunction add() {} /* plain\n block */\n// line\n",
+			want:     "/**\n * Adds.\n */\nfunction add() {}\n",
+		},
+		{
+			name:     "rust doc lines",
+			syn:      cLike,
+			keepDocs: true,
+			src:      "//! Crate docs.\n/// Item docs.\nfn f() {} // tail\n",
+			want:     "//! Crate docs.\n/// Item docs.\nfn f() {}\n",
+		},
+		{
+			name: "docs stripped without keepDocs",
+			syn:  cLike,
+			src:  "/// Item docs.\n/** block */\nfn f() {}\n",
+			want: "fn f() {}\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strip(tt.syn, tt.keepDocs, tt.src); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
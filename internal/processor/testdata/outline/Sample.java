@@ -0,0 +1,34 @@
+package example;
+
+import java.util.List;
+
+/**
+ * Sample shows a class with nested types. A brace in a doc comment: }
+ */
+public class Sample {
+    private static final String OPEN = "{";
+    private final List<String> names;
+
+    public enum Kind { A, B }
+
+    public Sample(List<String> names) {
+        this.names = names;
+    }
+
+    /** first returns the first name or "}" when empty. */
+    public String first() {
+        if (names.isEmpty()) {
+            return "}";
+        }
+        char c = '{';
+        return names.get(0) + c;
+    }
+
+    interface Visitor {
+        void visit(String name);
+    }
+
+    static class Inner {
+        int value() { return 1; }
+    }
+}
@@ -0,0 +1,31 @@
+#include <stdio.h>
+
+/* A brace in a block comment: { */
+#define OPEN "{"
+
+struct buffer {
+    char *data;
+    size_t len;
+};
+
+enum color { RED, GREEN };
+
+static const char *names[] = { "a", "}" };
+
+/* print writes buf to stdout. */
+void print(const struct buffer *buf)
+{
+    if (buf->len == 0) {
+        puts("}");
+        return;
+    }
+    fwrite(buf->data, 1, buf->len, stdout);
+}
+
+int main(void)
+{
+    char c = '{'; // a char literal with a brace
+    struct buffer b = { "hi", 2 };
+    print(&b);
+    return c == '{' ? 0 : 1;
+}
@@ -0,0 +1,38 @@
+// Package sample はアウトラインのテスト用です。
+package sample
+
+import (
+	"fmt"
+	"strings"
+)
+
+const greeting = "hello"
+
+var count int
+
+// Shape は図形です。
+type Shape interface {
+	// Area は面積を返します。
+	Area() float64
+}
+
+// Rect は長方形です。
+type Rect struct {
+	W, H float64 // 幅と高さ
+}
+
+// Area は面積を返します。
+func (r Rect) Area() float64 {
+	return r.W * r.H
+}
+
+// Greet は name への挨拶を返します。
+func Greet(name string) string {
+	count++
+	return fmt.Sprintf("%s, %s", greeting, strings.TrimSpace(name))
+}
+
+func helper[T any](v T) (T, error) {
+	// 本体のコメントは出力しない
+	return v, nil
+}
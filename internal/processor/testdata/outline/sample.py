@@ -0,0 +1,49 @@
+"""Module docstring with a def inside: def fake():"""
+
+import os
+
+TEMPLATE = """
+def not_a_function():
+    pass
+"""
+
+
+class Loader:
+    """Loads files.
+
+    The body below should be kept as a signature list.
+    """
+
+    suffix = ".txt"  # class attribute, with a # in the comment
+
+    def __init__(self, root):
+        self.root = root
+
+    def load(self, name,
+             encoding="utf-8"):
+        """Return the file contents.
+
+        Lines in the docstring stay.
+        """
+        path = os.path.join(self.root, name + self.suffix)
+        text = """
+multi-line string that starts at column 0
+"""
+        with open(path, encoding=encoding) as f:
+            return f.read() + text
+
+    async def fetch(self, url):
+        return url
+
+
+def helper(x): return x * 2
+
+
+def main():
+    # comment in the body
+    data = {"a": "}", "b": "{"}
+    print(Loader(".").load("a"), data)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,40 @@
+//! Crate docs with a brace: {
+
+use std::fmt;
+
+/// Point is a 2D point.
+pub struct Point {
+    pub x: i32,
+    pub y: i32,
+}
+
+pub enum Shape {
+    Circle { r: f64 },
+    Square(f64),
+}
+
+impl Point {
+    /// new creates a point.
+    pub fn new(x: i32, y: i32) -> Self {
+        Point { x, y }
+    }
+
+    pub fn label(&self) -> String {
+        let s = "}";
+        format!("{{{}, {}}} {}", self.x, self.y, s)
+    }
+}
+
+impl fmt::Display for Point {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        write!(f, "({}, {})", self.x, self.y)
+    }
+}
+
+pub trait Area {
+    fn area(&self) -> f64;
+}
+
+mod tests {
+    fn helper() -> char { '}' }
+}
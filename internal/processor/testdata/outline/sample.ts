@@ -0,0 +1,37 @@
+import { readFile, writeFile } from "fs";
+import type { Options } from "./options";
+
+/** Default separator; the braces in this string must not open a block. */
+const SEP = "{ }";
+
+// Config mirrors the JSON file. A brace in a comment: {
+export interface Config {
+  name: string;
+  tags?: string[];
+}
+
+export class Store {
+  private items: Map<string, string> = new Map();
+
+  /** get returns the value for key. */
+  get(key: string): string | undefined {
+    const msg = `missing ${key} in {store}`;
+    if (!this.items.has(key)) {
+      console.warn(msg);
+    }
+    return this.items.get(key);
+  }
+
+  set(key: string, value: string): void {
+    this.items.set(key, value + '}');
+  }
+}
+
+export function load(path: string): Config {
+  /* a block comment with } inside */
+  return JSON.parse(readFile(path, "utf8"));
+}
+
+export const defaults = { name: "x", tags: [] };
+
+export { Store as Cache };
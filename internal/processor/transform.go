@@ -0,0 +1,110 @@
+package processor
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// lineFilter は内容を1行ずつ変換します。
+type lineFilter interface {
+	// filterLine は改行を含む1行（最終行は改行を含まない場合があります）を受け取り、出力する内容を返します。
+	// nil を返した場合、その行は出力されません。引数のスライスは変更・再利用して構いません。
+	filterLine(line []byte) []byte
+}
+
+// lineFilterReader は r を1行ずつ読み込み、filters を順に適用した結果を返します。
+// ファイル全体を読み込まず、行単位のストリームとして変換します。
+type lineFilterReader struct {
+	br      *bufio.Reader
+	filters []lineFilter
+	buf     []byte // 未出力の変換結果
+	err     error
+}
+
+func newLineFilterReader(r io.Reader, filters ...lineFilter) io.Reader {
+	return &lineFilterReader{br: bufio.NewReader(r), filters: filters}
+}
+
+func (l *lineFilterReader) Read(p []byte) (int, error) {
+	for len(l.buf) == 0 {
+		if l.err != nil {
+			return 0, l.err
+		}
+		line, err := l.br.ReadBytes('\n')
+		l.err = err
+		for _, f := range l.filters {
+			if len(line) == 0 {
+				break
+			}
+			line = f.filterLine(line)
+		}
+		l.buf = line
+	}
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+	return n, nil
+}
+
+// blankCollapser は連続する空行（空白のみの行を含む）を1行にまとめます。
+type blankCollapser struct {
+	prevBlank bool
+}
+
+func (b *blankCollapser) filterLine(line []byte) []byte {
+	blank := isBlank(line)
+	if blank && b.prevBlank {
+		return nil
+	}
+	b.prevBlank = blank
+	return line
+}
+
+// isBlank は line が空白と改行のみからなるかを返します。
+func isBlank(line []byte) bool {
+	for _, c := range line {
+		switch c {
+		case ' ', '\t', '\r', '\n', '\f', '\v':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// minNumberWidth は行番号の最小の桁数です。
+const minNumberWidth = 4
+
+// lineNumberer は元の内容での行番号を、filters を適用した後の各行の先頭に付けます。
+// filters によって削除された行も数えるため、番号は常にファイル内の実際の行を指します。
+type lineNumberer struct {
+	width   int
+	format  func(n, width int) string
+	filters []lineFilter
+	n       int
+}
+
+func (l *lineNumberer) filterLine(line []byte) []byte {
+	l.n++
+	for _, f := range l.filters {
+		if len(line) == 0 {
+			return nil
+		}
+		line = f.filterLine(line)
+	}
+	if len(line) == 0 {
+		return nil
+	}
+	return append([]byte(l.format(l.n, l.width)), line...)
+}
+
+// defaultLineNumber は LineNumberFormatter を実装していない Formatter の行番号の書式です。
+func defaultLineNumber(n, width int) string {
+	return fmt.Sprintf("%*d | ", width, n)
+}
+
+// numberWidth は最後の行番号 last（不明な場合は 0）を右揃えで表示する桁数を返します。
+func numberWidth(last int) int {
+	return max(minNumberWidth, len(strconv.Itoa(last)))
+}
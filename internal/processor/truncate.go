@@ -0,0 +1,304 @@
+package processor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// DefaultTruncateLines は大容量ファイルを切り詰める場合に残す行数のデフォルト値です。
+const DefaultTruncateLines = 200
+
+// DefaultTruncateTailLines は TruncateHeadTail で末尾に残す行数のデフォルト値です。
+const DefaultTruncateTailLines = 50
+
+// DefaultTruncateBytes は TruncateBytes で残すバイト数のデフォルト値です。
+const DefaultTruncateBytes = 64 * 1024
+
+// TruncateMode は大容量ファイルの切り詰め方です。
+type TruncateMode int
+
+const (
+	// TruncateHead は先頭 Lines 行を残します。
+	TruncateHead TruncateMode = iota
+	// TruncateHeadTail は先頭 Lines 行と末尾 TailLines 行を残します。
+	TruncateHeadTail
+	// TruncateBytes は先頭 Bytes バイトを残します（UTF-8 の文字境界で切ります）。
+	TruncateBytes
+)
+
+// Truncation は切り詰めの設定です。ゼロ値は先頭 DefaultTruncateLines 行です。
+type Truncation struct {
+	Mode      TruncateMode
+	Lines     int   // 0 の場合は DefaultTruncateLines
+	TailLines int   // 0 の場合は DefaultTruncateTailLines
+	Bytes     int64 // 0 の場合は DefaultTruncateBytes
+}
+
+// withDefaults は 0 のフィールドをデフォルト値で埋めた設定を返します。
+func (t Truncation) withDefaults() Truncation {
+	if t.Lines <= 0 {
+		t.Lines = DefaultTruncateLines
+	}
+	if t.TailLines <= 0 {
+		t.TailLines = DefaultTruncateTailLines
+	}
+	if t.Bytes <= 0 {
+		t.Bytes = DefaultTruncateBytes
+	}
+	return t
+}
+
+// Describe は「何を残すか」を人間向けに説明します（対話プロンプト用）。
+func (t Truncation) Describe() string {
+	t = t.withDefaults()
+	switch t.Mode {
+	case TruncateHeadTail:
+		return fmt.Sprintf("the first %d and last %d lines", t.Lines, t.TailLines)
+	case TruncateBytes:
+		return fmt.Sprintf("the first %s bytes", formatCount(t.Bytes))
+	default:
+		return fmt.Sprintf("the first %d lines", t.Lines)
+	}
+}
+
+// reader は r を設定に従って切り詰め、末尾に注記を付加する Reader を返します。
+// 注記の件数（残りの行数・バイト数）は、残りのストリームを読み捨てながら数えます。
+func (t Truncation) reader(r io.Reader) io.Reader {
+	t = t.withDefaults()
+	switch t.Mode {
+	case TruncateHeadTail:
+		lr := newLineLimitReader(r, t.Lines)
+		return io.MultiReader(lr, &lazyReader{fn: func() (io.Reader, error) { return tailSection(lr.r, t.TailLines) }})
+	case TruncateBytes:
+		br := &byteLimitReader{r: r, remaining: t.Bytes}
+		return io.MultiReader(br, &lazyReader{fn: br.marker})
+	default:
+		lr := newLineLimitReader(r, t.Lines)
+		return io.MultiReader(lr, &lazyReader{fn: lr.marker})
+	}
+}
+
+// lineLimitReader は先頭 limit 行までを読み出し、それ以降は EOF を返します。
+// ファイル全体を読み込まず、ストリームのまま行数を数えます。
+type lineLimitReader struct {
+	r         io.Reader
+	remaining int // 残りの行数
+}
+
+func newLineLimitReader(r io.Reader, limit int) *lineLimitReader {
+	return &lineLimitReader{r: r, remaining: limit}
+}
+
+func (l *lineLimitReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		return 0, io.EOF
+	}
+
+	n, err := l.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] != '\n' {
+			continue
+		}
+		l.remaining--
+		if l.remaining == 0 {
+			// 改行を含めてここまでを返す。読み過ぎた分は残りとして r の先頭に戻す
+			if i+1 < n {
+				l.r = io.MultiReader(bytes.NewReader(append([]byte(nil), p[i+1:n]...)), l.r)
+			}
+			return i + 1, nil
+		}
+	}
+	return n, err
+}
+
+// marker は残りの行数を数え、切り詰めがあった場合のみ注記を返します。
+func (l *lineLimitReader) marker() (io.Reader, error) {
+	lines, _, err := countLines(l.r)
+	if err != nil || lines == 0 {
+		return nil, err
+	}
+	return markerReader(fmt.Sprintf("... (truncated, %s more lines)", formatCount(lines))), nil
+}
+
+// byteLimitReader は先頭 remaining バイトまでを読み出します。
+// 多バイト文字の途中で切らないよう、境界は UTF-8 の文字の先頭に合わせます。
+// 境界の判定には末尾の数バイトをまとめて見る必要があるため、最後の utf8.UTFMax バイトは tail に読み込んでから返します。
+type byteLimitReader struct {
+	r         io.Reader
+	remaining int64
+	tail      []byte // 読み込み済みで未返却の末尾
+	endsInEOL bool   // 最後に返したバイトが改行か
+}
+
+func (b *byteLimitReader) Read(p []byte) (int, error) {
+	if b.remaining > utf8.UTFMax {
+		if int64(len(p)) > b.remaining-utf8.UTFMax {
+			p = p[:b.remaining-utf8.UTFMax]
+		}
+		n, err := b.r.Read(p)
+		b.remaining -= int64(n)
+		if n > 0 {
+			b.endsInEOL = p[n-1] == '\n'
+		}
+		return n, err
+	}
+	if b.remaining > 0 {
+		if err := b.readTail(); err != nil {
+			return 0, err
+		}
+	}
+	if len(b.tail) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, b.tail)
+	b.tail = b.tail[n:]
+	if n > 0 {
+		b.endsInEOL = p[n-1] == '\n'
+	}
+	return n, nil
+}
+
+// readTail は残り remaining バイトを tail に読み込み、末尾の不完全な文字を r の先頭に戻します。
+func (b *byteLimitReader) readTail() error {
+	buf := make([]byte, b.remaining)
+	b.remaining = 0
+	n, err := io.ReadFull(b.r, buf)
+	buf = buf[:n]
+	switch err {
+	case nil:
+		start := n - 1
+		for start > 0 && !utf8.RuneStart(buf[start]) {
+			start--
+		}
+		if start >= 0 && utf8.RuneStart(buf[start]) && !utf8.FullRune(buf[start:]) {
+			b.r = io.MultiReader(bytes.NewReader(buf[start:]), b.r)
+			buf = buf[:start]
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		// ファイルが上限内で終わっているため、末尾はファイル本来の内容
+	default:
+		return err
+	}
+	b.tail = buf
+	return nil
+}
+
+// marker は残りのバイト数を数え、切り詰めがあった場合のみ注記を返します。
+func (b *byteLimitReader) marker() (io.Reader, error) {
+	_, rest, err := countLines(b.r)
+	if err != nil || rest == 0 {
+		return nil, err
+	}
+	text := fmt.Sprintf("... (truncated, %s more bytes)", formatCount(rest))
+	if !b.endsInEOL {
+		text = "\n" + text
+	}
+	return markerReader(text), nil
+}
+
+// tailSection は残りのストリームから末尾 n 行を保持し、省略した行数の注記と合わせて返します。
+// 保持するのは末尾 n 行のみで、それ以外は読み捨てます。
+func tailSection(r io.Reader, n int) (io.Reader, error) {
+	ring := make([][]byte, 0, n)
+	var total int64
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(line) > 0 {
+			total++
+			if len(ring) == n {
+				ring = append(ring[:0], ring[1:]...)
+			}
+			ring = append(ring, line)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if omitted := total - int64(len(ring)); omitted > 0 {
+		fmt.Fprintf(&buf, "... (truncated, %s lines omitted)\n", formatCount(omitted))
+	}
+	for _, line := range ring {
+		buf.Write(line)
+	}
+	return &buf, nil
+}
+
+// countLines は r を読み捨てながら行数（改行で終わらない最終行を含む）とバイト数を数えます。
+func countLines(r io.Reader) (lines, size int64, err error) {
+	buf := make([]byte, 32*1024)
+	last := byte('\n')
+	for {
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+			size += int64(n)
+			last = buf[n-1]
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return lines, size, rerr
+		}
+	}
+	if last != '\n' {
+		lines++
+	}
+	return lines, size, nil
+}
+
+// lazyReader は最初の Read で fn を呼び出して Reader を生成します。
+// 切り詰めの注記は本体を読み終えるまで内容が確定しないため、遅延評価します。
+type lazyReader struct {
+	fn func() (io.Reader, error)
+	r  io.Reader
+}
+
+func (l *lazyReader) Read(p []byte) (int, error) {
+	if l.r == nil {
+		r, err := l.fn()
+		if err != nil {
+			return 0, err
+		}
+		if r == nil {
+			r = bytes.NewReader(nil)
+		}
+		l.r = r
+	}
+	return l.r.Read(p)
+}
+
+func markerReader(text string) io.Reader {
+	return bytes.NewReader([]byte(text))
+}
+
+// formatCount は 12345 を "12,345" の形式に整形します。
+func formatCount(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	if len(s) <= 3 {
+		return s
+	}
+	var b bytes.Buffer
+	pre := len(s) % 3
+	if pre > 0 {
+		b.WriteString(s[:pre])
+	}
+	for i := pre; i < len(s); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(s[i : i+3])
+	}
+	return b.String()
+}
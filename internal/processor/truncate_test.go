@@ -0,0 +1,116 @@
+package processor
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestTruncationReader(t *testing.T) {
+	tests := []struct {
+		name string
+		tr   Truncation
+		in   string
+		want string
+	}{
+		{
+			name: "head",
+			tr:   Truncation{Lines: 2},
+			in:   "a\nb\nc\nd\n",
+			want: "a\nb\n... (truncated, 2 more lines)",
+		},
+		{
+			name: "head without trailing newline",
+			tr:   Truncation{Lines: 2},
+			in:   "a\nb\nc",
+			want: "a\nb\n... (truncated, 1 more lines)",
+		},
+		{
+			// 切り詰めがなければ注記を付けない
+			name: "head fits",
+			tr:   Truncation{Lines: 2},
+			in:   "a\nb\n",
+			want: "a\nb\n",
+		},
+		{
+			name: "head count",
+			tr:   Truncation{Lines: 2},
+			in:   strings.Repeat("x\n", 1500),
+			want: "x\nx\n... (truncated, 1,498 more lines)",
+		},
+		{
+			name: "head-tail",
+			tr:   Truncation{Mode: TruncateHeadTail, Lines: 1, TailLines: 2},
+			in:   "1\n2\n3\n4\n5\n",
+			want: "1\n... (truncated, 2 lines omitted)\n4\n5\n",
+		},
+		{
+			name: "head-tail fits",
+			tr:   Truncation{Mode: TruncateHeadTail, Lines: 1, TailLines: 2},
+			in:   "1\n2\n3\n",
+			want: "1\n2\n3\n",
+		},
+		{
+			name: "bytes",
+			tr:   Truncation{Mode: TruncateBytes, Bytes: 4},
+			in:   "abcdef\n",
+			want: "abcd\n... (truncated, 3 more bytes)",
+		},
+		{
+			// 改行で切れた場合は注記の前に改行を足さない
+			name: "bytes at newline",
+			tr:   Truncation{Mode: TruncateBytes, Bytes: 3},
+			in:   "ab\ncd",
+			want: "ab\n... (truncated, 2 more bytes)",
+		},
+		{
+			// 多バイト文字の途中では切らない
+			name: "bytes rune boundary",
+			tr:   Truncation{Mode: TruncateBytes, Bytes: 3},
+			in:   "aあい",
+			want: "a\n... (truncated, 6 more bytes)",
+		},
+		{
+			name: "bytes rune boundary after prefix",
+			tr:   Truncation{Mode: TruncateBytes, Bytes: 6},
+			in:   "abcdあい",
+			want: "abcd\n... (truncated, 6 more bytes)",
+		},
+		{
+			name: "bytes fits",
+			tr:   Truncation{Mode: TruncateBytes, Bytes: 4},
+			in:   "abcd",
+			want: "abcd",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, r := range []io.Reader{strings.NewReader(tt.in), iotest.OneByteReader(strings.NewReader(tt.in))} {
+				got, err := io.ReadAll(tt.tr.reader(r))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != tt.want {
+					t.Errorf("got %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncationDescribe(t *testing.T) {
+	tests := []struct {
+		tr   Truncation
+		want string
+	}{
+		{Truncation{}, "the first 200 lines"},
+		{Truncation{Mode: TruncateHeadTail, Lines: 10}, "the first 10 and last 50 lines"},
+		{Truncation{Mode: TruncateBytes}, "the first 65,536 bytes"},
+	}
+	for _, tt := range tests {
+		if got := tt.tr.Describe(); got != tt.want {
+			t.Errorf("Describe(%+v) = %q, want %q", tt.tr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package secret
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// rule は1行の中から秘密情報を検出する規則です。
+type rule struct {
+	id string
+	// pattern の最初のサブマッチ（なければ一致全体）を秘密情報として置き換えます。
+	pattern *regexp.Regexp
+	// check は検出した値が秘密情報らしいかを判定します（nil の場合は常に秘密情報とみなします）。
+	check func(value string) bool
+}
+
+// PrivateKeyRule は PEM 形式の秘密鍵ブロックの規則 ID です。
+// ブロックは複数行にわたるため、他の規則と異なり Redactor が状態を持って扱います。
+const PrivateKeyRule = "private-key"
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN[A-Z0-9 ]* PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END[A-Z0-9 ]* PRIVATE KEY( BLOCK)?-----`)
+)
+
+// defaultRules は組み込みの規則です（秘密鍵のブロックを除く）。
+var defaultRules = []rule{
+	{id: "aws-access-key-id", pattern: regexp.MustCompile(`\b((?:AKIA|ASIA|ABIA|ACCA)[A-Z0-9]{16})\b`)},
+	{id: "aws-secret-access-key", pattern: regexp.MustCompile(`(?i)aws[\w.-]{0,20}secret[\w.-]{0,20}["']?\s*(?::=|=|:)\s*["']?([A-Za-z0-9/+=]{40})(?:[^A-Za-z0-9/+=]|$)`)},
+	{id: "github-token", pattern: regexp.MustCompile(`\b((?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36,255}|github_pat_[A-Za-z0-9_]{22,255})\b`)},
+	{id: "slack-token", pattern: regexp.MustCompile(`\b(xox[abposr]-[A-Za-z0-9-]{10,})\b`)},
+	{id: "jwt", pattern: regexp.MustCompile(`\b(eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,})`)},
+	{
+		// password = "..."、token: ... など、秘密情報を表す名前に代入された値
+		id:      "generic-secret",
+		pattern: regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|auth[_-]?key|credentials?)[\w.-]*["']?\s*(?::=|=|:)\s*["'` + "`" + `]?([A-Za-z0-9+/=_\-.!@#$%^&*~]{8,})(?:["'` + "`" + `\s,;]|$)`),
+		check:   highEntropy,
+	},
+}
+
+// highEntropy は値が識別子や定型の文字列ではなく、ランダムな文字列らしいかを返します。
+// 英字と数字を両方含み、1文字あたりのエントロピーが 3 ビット以上のもの（または 4 ビット以上のもの）とします。
+func highEntropy(value string) bool {
+	e := entropy(value)
+	if e >= 4 {
+		return true
+	}
+	return e >= 3 && strings.ContainsAny(value, "0123456789") && strings.IndexFunc(value, isLetter) >= 0
+}
+
+func isLetter(r rune) bool {
+	return ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')
+}
+
+// entropy は s のシャノンエントロピー（1文字あたりのビット数）を返します。
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len([]rune(s)))
+	var e float64
+	for _, c := range counts {
+		p := float64(c) / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
+
+// Scanner は規則と許可リストを保持します。
+type Scanner struct {
+	rules []rule
+	allow []*regexp.Regexp
+}
+
+// NewScanner は組み込みの規則で Scanner を作成します。
+// allowlist は正規表現のリストで、検出した値がいずれかに一致する場合は置き換えません（テスト用のダミー値など）。
+func NewScanner(allowlist []string) (*Scanner, error) {
+	s := &Scanner{rules: defaultRules}
+	for _, pattern := range allowlist {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowlist pattern %q: %w", pattern, err)
+		}
+		s.allow = append(s.allow, re)
+	}
+	return s, nil
+}
+
+// ReadAllowlist は1行に1つの正規表現を並べた許可リストを読み込みます。空行と # で始まる行は無視します。
+func ReadAllowlist(r io.Reader) ([]string, error) {
+	var patterns []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, sc.Err()
+}
+
+func (s *Scanner) allowed(value []byte) bool {
+	for _, re := range s.allow {
+		if re.Match(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redactor は内容を1行ずつ受け取り、秘密情報を [REDACTED:rule-id] に置き換えます。
+// 秘密鍵のブロックは BEGIN の位置に1つの置き換えを出力し、END までの内容は出力しません。
+type Redactor struct {
+	s     *Scanner
+	inKey bool
+}
+
+// NewRedactor は1つのファイル（内容の先頭から）用の Redactor を作成します。
+func (s *Scanner) NewRedactor() *Redactor {
+	return &Redactor{s: s}
+}
+
+// Redact は改行を含む1行を受け取り、置き換え後の行と検出した規則の ID を返します。
+// 戻り値の行が nil の場合、その行は出力しません（秘密鍵のブロックの途中）。
+func (r *Redactor) Redact(line []byte) ([]byte, []string) {
+	var (
+		out   []byte
+		found []string
+	)
+	for {
+		if r.inKey {
+			loc := privateKeyEnd.FindIndex(line)
+			if loc == nil {
+				if out == nil {
+					return nil, found
+				}
+				return append(out, lineEnding(line)...), found
+			}
+			r.inKey = false
+			line = line[loc[1]:]
+			// END のみの行は、置き換えを出力した BEGIN の行に続く空行にしない
+			if out == nil && len(line) == len(lineEnding(line)) {
+				return nil, found
+			}
+			continue
+		}
+		loc := privateKeyBegin.FindIndex(line)
+		if loc == nil {
+			break
+		}
+		before, ids := r.s.redactLine(line[:loc[0]])
+		out = append(append(out, before...), redacted(PrivateKeyRule)...)
+		found = append(append(found, ids...), PrivateKeyRule)
+		r.inKey = true
+		line = line[loc[1]:]
+	}
+	rest, ids := r.s.redactLine(line)
+	return append(out, rest...), append(found, ids...)
+}
+
+// SetInKeyBlock は秘密鍵のブロックの内側にいるかを設定します。
+// 差分のハンクのように、内容の途中から Redact を始める場合に使用します。
+func (r *Redactor) SetInKeyBlock(in bool) {
+	r.inKey = in
+}
+
+// base64Line は PEM の本文の1行です。
+var base64Line = regexp.MustCompile(`^[A-Za-z0-9+/=]{16,}\r?$`)
+
+// InKeyBlock は内容の途中の行 lines が、秘密鍵のブロックの内側から始まっているかを返します。
+// BEGIN より先に END がある場合、またはどちらもなく全ての行が PEM の本文らしい場合に true です。
+func InKeyBlock(lines [][]byte) bool {
+	body := false
+	for _, line := range lines {
+		switch {
+		case privateKeyEnd.Match(line):
+			return true
+		case privateKeyBegin.Match(line):
+			return false
+		}
+		line = bytes.TrimRight(line, "\n")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if !base64Line.Match(line) {
+			return false
+		}
+		body = true
+	}
+	return body
+}
+
+// Redacted は規則 id による置き換え後の文字列（[REDACTED:id]）を返します。
+func Redacted(id string) string {
+	return redacted(id)
+}
+
+// redactLine は秘密鍵以外の規則を順に適用します。
+func (s *Scanner) redactLine(line []byte) ([]byte, []string) {
+	var found []string
+	for _, rule := range s.rules {
+		matches := rule.pattern.FindAllSubmatchIndex(line, -1)
+		if matches == nil {
+			continue
+		}
+		var out []byte
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			value := line[start:end]
+			if (rule.check != nil && !rule.check(string(value))) || s.allowed(value) {
+				continue
+			}
+			out = append(out, line[last:start]...)
+			out = append(out, redacted(rule.id)...)
+			last = end
+			found = append(found, rule.id)
+		}
+		if last > 0 {
+			line = append(out, line[last:]...)
+		}
+	}
+	return line, found
+}
+
+func redacted(id string) string {
+	return "[REDACTED:" + id + "]"
+}
+
+// lineEnding は line の末尾の改行（\n または \r\n）を返します。
+func lineEnding(line []byte) []byte {
+	switch {
+	case len(line) >= 2 && line[len(line)-2] == '\r' && line[len(line)-1] == '\n':
+		return line[len(line)-2:]
+	case len(line) >= 1 && line[len(line)-1] == '\n':
+		return line[len(line)-1:]
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package source
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// StdinSpec はソースとして標準入力を指定する際の文字列です。
+const StdinSpec = "-"
+
+// Source は走査対象のファイルシステムと、その後始末を保持します。
+// ディレクトリ・アーカイブ・標準入力のいずれから作成しても、Processor からは同じ fs.FS として扱えます。
+type Source struct {
+	FS  fs.FS
+	Dir string // ディスク上のディレクトリ（自己参照判定用。アーカイブの場合は空）
+
+	cleanup []func() error
+}
+
+// Open は spec からソースを開きます。
+//   - "-"       : 標準入力から zip / tar / tar.gz を読み込む
+//   - ディレクトリ : os.DirFS
+//   - ファイル    : 先頭バイトから zip / tar / tar.gz を判別
+func Open(spec string, stdin io.Reader) (*Source, error) {
+	if spec == StdinSpec {
+		return openStream(stdin)
+	}
+
+	info, err := os.Stat(spec)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &Source{FS: os.DirFS(spec), Dir: spec}, nil
+	}
+
+	f, err := os.Open(spec)
+	if err != nil {
+		return nil, err
+	}
+	s := &Source{cleanup: []func() error{f.Close}}
+	if err := s.openArchive(f, info.Size()); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("%s: %w", spec, err)
+	}
+	return s, nil
+}
+
+// Close はアーカイブファイルと一時ファイルを後始末します。
+func (s *Source) Close() error {
+	var errs []error
+	// 登録と逆順に実行する（Close してから Remove）
+	for i := len(s.cleanup) - 1; i >= 0; i-- {
+		errs = append(errs, s.cleanup[i]())
+	}
+	s.cleanup = nil
+	return errors.Join(errs...)
+}
+
+// openStream は標準入力の内容を一時ファイルへ退避してから開きます。
+// zip の読み込みや tar のランダムアクセスにはシーク可能なファイルが必要なためです。
+func openStream(r io.Reader) (*Source, error) {
+	s := &Source{}
+	tmp, err := s.createTemp()
+	if err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("reading stdin: %w", err)
+	}
+	if err := s.openArchive(tmp, size); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("stdin: %w", err)
+	}
+	return s, nil
+}
+
+// openArchive は先頭バイトで形式を判別し、s.FS を設定します。
+func (s *Source) openArchive(f *os.File, size int64) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	head, err := bufio.NewReader(f).Peek(512)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		zr, err := zip.NewReader(f, size)
+		if err != nil {
+			return err
+		}
+		s.FS = zr
+		return nil
+
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		// tar.gz: 展開後の tar を一時ファイルに書き出してからインデックスを作成する
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		tmp, err := s.createTemp()
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(tmp, gz); err != nil {
+			return err
+		}
+		return s.openTar(tmp)
+
+	case isTar(head):
+		return s.openTar(f)
+	}
+
+	return errors.New("unsupported source format (want a directory, zip, tar or tar.gz)")
+}
+
+func (s *Source) openTar(f *os.File) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	tfs, err := newTarFS(f)
+	if err != nil {
+		return err
+	}
+	s.FS = tfs
+	return nil
+}
+
+// createTemp は Close 時に削除される一時ファイルを作成します。
+func (s *Source) createTemp() (*os.File, error) {
+	tmp, err := os.CreateTemp("", "codepack-*")
+	if err != nil {
+		return nil, err
+	}
+	name := tmp.Name()
+	s.cleanup = append(s.cleanup, func() error { return os.Remove(name) }, tmp.Close)
+	return tmp, nil
+}
+
+// isTar は POSIX ustar / GNU tar のマジック（オフセット257）を確認します。
+func isTar(head []byte) bool {
+	return len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar"))
+}
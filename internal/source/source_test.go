@@ -0,0 +1,73 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpen(t *testing.T) {
+	tarData := buildTar(t, []tarEntryFile{
+		{name: "app/main.go", body: "package main\n", typeflag: tar.TypeReg},
+	})
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(tarData)
+	zw.Close()
+
+	var zipData bytes.Buffer
+	w := zip.NewWriter(&zipData)
+	f, err := w.Create("app/main.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("package main\n"))
+	w.Close()
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"tar", tarData},
+		{"tar.gz", gz.Bytes()},
+		{"zip", zipData.Bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "src."+tt.name)
+			if err := os.WriteFile(file, tt.data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			for _, spec := range []string{file, StdinSpec} {
+				s, err := Open(spec, bytes.NewReader(tt.data))
+				if err != nil {
+					t.Fatalf("Open(%s): %v", spec, err)
+				}
+				got, err := fs.ReadFile(s.FS, "app/main.go")
+				if err != nil || string(got) != "package main\n" {
+					t.Errorf("Open(%s): ReadFile = %q, %v", spec, got, err)
+				}
+				if s.Dir != "" {
+					t.Errorf("Open(%s): Dir = %q, want empty for an archive", spec, s.Dir)
+				}
+				if err := s.Close(); err != nil {
+					t.Errorf("Open(%s): Close: %v", spec, err)
+				}
+			}
+		})
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := Open(StdinSpec, strings.NewReader("plain text"))
+		if err == nil || !strings.Contains(err.Error(), "unsupported source format") {
+			t.Errorf("Open = %v, want an unsupported format error", err)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package source
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tarFS は tar アーカイブを fs.FS として公開します。
+// 起動時に一度だけアーカイブを走査して各エントリのデータ位置を記録し、
+// 読み込み時は io.SectionReader で該当範囲のみを読み出します（内容はメモリに展開しません）。
+type tarFS struct {
+	ra       io.ReaderAt
+	entries  map[string]*tarEntry // Key: ルートからの相対パス（ディレクトリを含む）
+	children map[string][]string  // Key: ディレクトリパス, Value: ソート済みの子要素名
+}
+
+// tarEntry はアーカイブ内の1エントリ（または合成したディレクトリ）を表します。
+type tarEntry struct {
+	name    string
+	offset  int64
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+// countingReader は読み込んだバイト数を数えます。
+// tar.Reader は Next() の時点でヘッダーまでしか読まないため、その時点の位置がデータの開始位置になります。
+// Seek を実装しないことで、tar.Reader に読み飛ばしも Read 経由で行わせます。
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// newTarFS は非圧縮の tar を走査し、インデックスを作成します。
+// r は先頭位置にシークされている必要があります。
+func newTarFS(r interface {
+	io.Reader
+	io.ReaderAt
+}) (*tarFS, error) {
+	t := &tarFS{
+		ra:       r,
+		entries:  map[string]*tarEntry{".": {name: ".", mode: fs.ModeDir | 0o755}},
+		children: make(map[string][]string),
+	}
+
+	counter := &countingReader{r: r}
+	tr := tar.NewReader(counter)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := cleanArchivePath(hdr.Name)
+		if name == "" || name == "." {
+			continue
+		}
+
+		var mode fs.FileMode
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			mode = fs.FileMode(hdr.Mode).Perm()
+		case tar.TypeDir:
+			mode = fs.ModeDir | fs.FileMode(hdr.Mode).Perm()
+		case tar.TypeSymlink, tar.TypeLink:
+			// リンクはディスク上のシンボリックリンクと同様にスキップ対象とする
+			mode = fs.ModeSymlink | 0o777
+		default:
+			// デバイスファイルやスパースファイルなどは対象外
+			continue
+		}
+
+		t.add(name, &tarEntry{
+			name:    path.Base(name),
+			offset:  counter.n,
+			size:    hdr.Size,
+			mode:    mode,
+			modTime: hdr.ModTime,
+		})
+	}
+
+	for _, names := range t.children {
+		sort.Strings(names)
+	}
+	return t, nil
+}
+
+// cleanArchivePath は "./a/b" や "/a/b" を fs.FS 形式の "a/b" に正規化します。
+func cleanArchivePath(name string) string {
+	name = path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+	return strings.TrimPrefix(name, "/")
+}
+
+// add はエントリを登録し、親ディレクトリを必要に応じて合成します。
+// 同名のエントリが複数ある場合は tar の仕様どおり後勝ちとします。
+func (t *tarFS) add(name string, e *tarEntry) {
+	if old, exists := t.entries[name]; exists {
+		if old.mode.IsDir() && e.mode.IsDir() {
+			return
+		}
+		t.entries[name] = e
+		return
+	}
+	t.entries[name] = e
+
+	for name != "." {
+		parent := path.Dir(name)
+		t.children[parent] = append(t.children[parent], path.Base(name))
+		if _, exists := t.entries[parent]; exists {
+			return
+		}
+		t.entries[parent] = &tarEntry{name: path.Base(parent), mode: fs.ModeDir | 0o755}
+		name = parent
+	}
+}
+
+// Open は fs.FS の実装です。
+func (t *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	e, ok := t.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if e.mode.IsDir() {
+		return &tarDir{fs: t, path: name, entry: e}, nil
+	}
+	return &tarFile{entry: e, SectionReader: io.NewSectionReader(t.ra, e.offset, e.size)}, nil
+}
+
+// ReadDir は fs.ReadDirFS の実装です。
+func (t *tarFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	e, ok := t.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	if !e.mode.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+
+	names := t.children[name]
+	list := make([]fs.DirEntry, 0, len(names))
+	for _, child := range names {
+		list = append(list, fs.FileInfoToDirEntry(t.entries[path.Join(name, child)]))
+	}
+	return list, nil
+}
+
+// --- fs.FileInfo の実装 ---
+
+func (e *tarEntry) Name() string       { return e.name }
+func (e *tarEntry) Size() int64        { return e.size }
+func (e *tarEntry) Mode() fs.FileMode  { return e.mode }
+func (e *tarEntry) ModTime() time.Time { return e.modTime }
+func (e *tarEntry) IsDir() bool        { return e.mode.IsDir() }
+func (e *tarEntry) Sys() any           { return nil }
+
+// tarFile はアーカイブ内の通常ファイルです。
+type tarFile struct {
+	*io.SectionReader
+	entry *tarEntry
+}
+
+func (f *tarFile) Stat() (fs.FileInfo, error) { return f.entry, nil }
+func (f *tarFile) Close() error               { return nil }
+
+// tarDir はアーカイブ内のディレクトリです。
+type tarDir struct {
+	fs     *tarFS
+	path   string
+	entry  *tarEntry
+	offset int
+}
+
+func (d *tarDir) Stat() (fs.FileInfo, error) { return d.entry, nil }
+func (d *tarDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.path, Err: errors.New("is a directory")}
+}
+func (d *tarDir) Close() error { return nil }
+
+// ReadDir は fs.ReadDirFile の実装です。
+func (d *tarDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	all, err := d.fs.ReadDir(d.path)
+	if err != nil {
+		return nil, err
+	}
+	rest := all[d.offset:]
+	if n <= 0 {
+		d.offset = len(all)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
@@ -0,0 +1,91 @@
+package source
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// tarEntryFile はテスト用アーカイブの1エントリです。
+type tarEntryFile struct {
+	name     string
+	body     string
+	typeflag byte
+}
+
+// buildTar は entries を順に書き込んだ tar を返します。
+func buildTar(t *testing.T, entries []tarEntryFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.body)), Typeflag: e.typeflag}
+		switch e.typeflag {
+		case tar.TypeDir:
+			hdr.Mode = 0o755
+		case tar.TypeSymlink:
+			hdr.Linkname = e.body
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if e.typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestTarFS(t *testing.T) {
+	data := buildTar(t, []tarEntryFile{
+		{name: "./README.md", body: "# old\n", typeflag: tar.TypeReg},
+		{name: "src/", typeflag: tar.TypeDir},
+		{name: "src/main.go", body: "package main\n", typeflag: tar.TypeReg},
+		// 親ディレクトリのエントリがないファイル
+		{name: "/docs/guide/intro.md", body: "intro\n", typeflag: tar.TypeReg},
+		{name: "link", body: "README.md", typeflag: tar.TypeSymlink},
+		// 同名のエントリは後勝ち
+		{name: "README.md", body: "# new\n", typeflag: tar.TypeReg},
+		{name: "src", typeflag: tar.TypeDir},
+	})
+	tfs, err := newTarFS(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fstest.TestFS(tfs, "README.md", "src/main.go", "docs/guide/intro.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := fs.ReadFile(tfs, "README.md")
+	if err != nil || string(got) != "# new\n" {
+		t.Errorf("ReadFile(README.md) = %q, %v, want the last entry", got, err)
+	}
+
+	// 重複したエントリがディレクトリ一覧に二重に現れないこと
+	entries, err := fs.ReadDir(tfs, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if got, want := strings.Join(names, " "), "README.md docs link src"; got != want {
+		t.Errorf("ReadDir(.) = %s, want %s", got, want)
+	}
+
+	info, err := fs.Stat(tfs, "link")
+	if err != nil || info.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("Stat(link) = %v, %v, want a symlink", info, err)
+	}
+}
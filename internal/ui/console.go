@@ -6,13 +6,29 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"path"
 	"strings"
+
+	"github.com/kazuki-sk/codepack/internal/processor"
+)
+
+// LargeFilePolicy は大容量ファイルの扱いの方針です (--large-policy)。
+type LargeFilePolicy string
+
+const (
+	PolicyAsk      LargeFilePolicy = "ask"      // ファイルごとに確認する（対話できない場合は除外）
+	PolicyInclude  LargeFilePolicy = "include"  // 確認せず全て含める
+	PolicySkip     LargeFilePolicy = "skip"     // 確認せず全て除外する
+	PolicyTruncate LargeFilePolicy = "truncate" // 確認せず先頭のみを含める
 )
 
+// NonInteractiveDefault は PolicyAsk で対話できない場合（CI、パイプ、cron）の扱いです。
+// 実行環境によって結果が変わらないよう、常に除外とします。
+const NonInteractiveDefault = processor.LargeSkip
+
 // LargeFileOptions は大容量ファイル処理に必要な設定オプションを定義します。
 type LargeFileOptions struct {
-	ForceLarge bool
-	SkipLarge  bool
+	Policy LargeFilePolicy // 空の場合は PolicyAsk
 }
 
 // Console はCLIにおけるユーザーとの対話を管理します。
@@ -21,6 +37,12 @@ type Console struct {
 	out    io.Writer
 	reader *bufio.Reader // レビュー対応: バッファを保持して再利用する
 	opts   LargeFileOptions
+
+	progress *Progress // 進捗表示（無効な場合は nil）
+
+	// 対話で選択された「以降の全ファイル」「拡張子ごと」の扱い
+	all   *processor.LargeFileDecision
+	byExt map[string]processor.LargeFileDecision
 }
 
 // NewConsole は新しい Console インスタンスを初期化します。
@@ -33,60 +55,187 @@ func NewConsole(in InputPort, out io.Writer, opts LargeFileOptions) *Console {
 	}
 }
 
+// AttachProgress は進捗表示を関連付けます。
+// プロンプトの表示・入力中は進捗行の描画を止め、表示が崩れないようにします。
+func (c *Console) AttachProgress(p *Progress) {
+	c.progress = p
+}
+
 // Close はConsoleとしてのクリーンアップを行いますが、
 // InputPortのClose責務は所有者(main)にあるため、ここでは何もしません。
 func (c *Console) Close() error {
 	return nil
 }
 
+// previewLines はプロンプトの "p" で表示する行数です。
+const previewLines = 20
+
 // ShouldInclude は大容量ファイルを処理対象に含めるかどうかをユーザーまたは設定に基づいて判定します。
+// 切り詰めの判断は「含める」として扱います。
 func (c *Console) ShouldInclude(ctx context.Context, path string, size int64) (bool, error) {
-	// 1. 強制包含フラグの確認
-	if c.opts.ForceLarge {
-		return true, nil
+	decision, err := c.DecideLarge(ctx, processor.LargeFile{Path: path, Size: size})
+	return decision != processor.LargeSkip, err
+}
+
+// DecideLarge は大容量ファイルの扱いを方針またはユーザーの回答に基づいて決定します。
+// processor.LargeFileDecider の実装です。
+func (c *Console) DecideLarge(ctx context.Context, f processor.LargeFile) (processor.LargeFileDecision, error) {
+	// 1. 方針による決定
+	switch c.opts.Policy {
+	case PolicyInclude:
+		return processor.LargeInclude, nil
+	case PolicySkip:
+		return processor.LargeSkip, nil
+	case PolicyTruncate:
+		return processor.LargeTruncate, nil
+	}
+
+	// 2. 以前の回答（"a" / "n!" / "e"）による決定
+	if c.all != nil {
+		return *c.all, nil
+	}
+	ext := strings.ToLower(path.Ext(f.Path))
+	if d, ok := c.byExt[ext]; ok {
+		return d, nil
 	}
 
-	// 2. 強制除外フラグの確認
-	if c.opts.SkipLarge {
-		return false, nil
+	// 3. 対話できない場合は決定的なデフォルトを使用（プロンプトを出さず、入力も読まない）
+	if !c.in.IsInteractive() {
+		return NonInteractiveDefault, nil
 	}
 
-	// 3. 対話的確認
+	// 4. 対話的確認
 	if err := ctx.Err(); err != nil {
-		return false, err
+		return processor.LargeSkip, err
+	}
+
+	if c.progress != nil {
+		c.progress.Pause()
+		defer c.progress.Resume()
 	}
 
-	c.printPrompt(path, size)
+	c.printPrompt(f.Path, f.Size)
+	for {
+		input, err := c.readAnswer(ctx)
+		if err != nil {
+			return processor.LargeSkip, err
+		}
+
+		switch input {
+		case "y", "yes":
+			return processor.LargeInclude, nil
+		case "", "n", "no":
+			// デフォルトは No
+			return processor.LargeSkip, nil
+		case "a", "all":
+			return c.remember(processor.LargeInclude), nil
+		case "n!":
+			return c.remember(processor.LargeSkip), nil
+		case "t":
+			return processor.LargeTruncate, nil
+		case "e":
+			return c.askForExtension(ctx, ext, f.Truncation.Describe())
+		case "p":
+			c.printPreview(f)
+		default:
+			c.printHelp(f.Truncation.Describe())
+		}
+		fmt.Fprint(c.out, "    Include this file? [y/N/a/n!/t/e/p/?]: ")
+	}
+}
 
+// askForExtension は同じ拡張子の全ファイルに適用する扱いを確認し、記憶します。
+func (c *Console) askForExtension(ctx context.Context, ext string, kept string) (processor.LargeFileDecision, error) {
+	label := "*" + ext
+	if ext == "" {
+		label = "files without an extension"
+	}
+
+	for {
+		fmt.Fprintf(c.out, "    Apply to every large %s: include, skip or keep only %s? [y/n/t]: ", label, kept)
+		input, err := c.readAnswer(ctx)
+		if err != nil {
+			return processor.LargeSkip, err
+		}
+
+		var d processor.LargeFileDecision
+		switch input {
+		case "y", "yes":
+			d = processor.LargeInclude
+		case "n", "no":
+			d = processor.LargeSkip
+		case "t":
+			d = processor.LargeTruncate
+		default:
+			continue
+		}
+		if c.byExt == nil {
+			c.byExt = make(map[string]processor.LargeFileDecision)
+		}
+		c.byExt[ext] = d
+		return d, nil
+	}
+}
+
+// remember は以降の全ての大容量ファイルに d を適用します。
+func (c *Console) remember(d processor.LargeFileDecision) processor.LargeFileDecision {
+	c.all = &d
+	return d
+}
+
+// readAnswer は1行を読み込み、小文字化・空白除去した回答を返します。
+func (c *Console) readAnswer(ctx context.Context) (string, error) {
 	// 保持しているリーダーを使用
 	line, err := c.reader.ReadString('\n')
 
 	if err != nil {
 		// コンテキストキャンセルが原因のエラーかどうかを確認
 		if ctx.Err() != nil {
-			return false, ctx.Err()
+			return "", ctx.Err()
 		}
 		// InputPort.Close() による意図的な中断の場合
 		// レビュー指摘対応: 具体的なエラー型を隠蔽し、キャンセルとして正規化する
 		if errors.Is(err, ErrInputClosed) {
-			return false, context.Canceled
+			return "", context.Canceled
 		}
-		return false, err
+		return "", err
 	}
 
-	input := strings.TrimSpace(strings.ToLower(line))
-	if input == "y" || input == "yes" {
-		return true, nil
-	}
-
-	// デフォルトは No
-	return false, nil
+	return strings.TrimSpace(strings.ToLower(line)), nil
 }
 
 // printPrompt はユーザーに確認メッセージを表示します。
 func (c *Console) printPrompt(path string, size int64) {
 	humanSize := formatSize(size)
-	fmt.Fprintf(c.out, "\n[?] Large file detected: %s (%s)\n    Include this file? [y/N]: ", path, humanSize)
+	fmt.Fprintf(c.out, "\n[?] Large file detected: %s (%s)\n    Include this file? [y/N/a/n!/t/e/p/?]: ", path, humanSize)
+}
+
+// printPreview はファイルの先頭数行を表示します。
+func (c *Console) printPreview(f processor.LargeFile) {
+	head, err := f.Head(previewLines)
+	if err != nil {
+		fmt.Fprintf(c.out, "    (preview unavailable: %v)\n", err)
+		return
+	}
+	fmt.Fprintf(c.out, "    --- first %d lines of %s ---\n", previewLines, f.Path)
+	for _, line := range strings.SplitAfter(head, "\n") {
+		if line != "" {
+			fmt.Fprint(c.out, "    | ", strings.TrimRight(line, "\r\n"), "\n")
+		}
+	}
+	fmt.Fprintln(c.out, "    ---")
+}
+
+// printHelp は回答の選択肢を説明します。
+func (c *Console) printHelp(kept string) {
+	fmt.Fprintf(c.out, `    y  include this file
+    n  skip this file (default)
+    a  include this and all remaining large files
+    n! skip this and all remaining large files
+    t  include only %s
+    e  choose for every large file with this extension
+    p  preview the first %d lines
+`, kept, previewLines)
 }
 
 // formatSize はバイトサイズを人間が読みやすい形式に変換します。
@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kazuki-sk/codepack/internal/processor"
+)
+
+// fakeInput は回答をあらかじめ与えた InputPort です。
+type fakeInput struct {
+	r           *strings.Reader
+	interactive bool
+	read        bool // Read が呼ばれたか
+}
+
+func newFakeInput(answers string, interactive bool) *fakeInput {
+	return &fakeInput{r: strings.NewReader(answers), interactive: interactive}
+}
+
+func (f *fakeInput) Read(p []byte) (int, error) {
+	f.read = true
+	return f.r.Read(p)
+}
+
+func (f *fakeInput) Close() error        { return nil }
+func (f *fakeInput) IsInteractive() bool { return f.interactive }
+
+func decide(t *testing.T, c *Console, name string) processor.LargeFileDecision {
+	t.Helper()
+	d, err := c.DecideLarge(context.Background(), processor.LargeFile{Path: name, Size: 2 << 20})
+	if err != nil {
+		t.Fatalf("DecideLarge(%s): %v", name, err)
+	}
+	return d
+}
+
+func TestConsoleNonInteractive(t *testing.T) {
+	in := newFakeInput("y\n", false)
+	var out strings.Builder
+	c := NewConsole(in, &out, LargeFileOptions{})
+
+	if d := decide(t, c, "big.log"); d != NonInteractiveDefault {
+		t.Errorf("decision = %v, want %v", d, NonInteractiveDefault)
+	}
+	if in.read {
+		t.Error("input was read although the port is not interactive")
+	}
+	if out.Len() != 0 {
+		t.Errorf("prompt was printed: %q", out.String())
+	}
+}
+
+func TestConsolePolicy(t *testing.T) {
+	tests := []struct {
+		policy      LargeFilePolicy
+		interactive bool
+		want        processor.LargeFileDecision
+	}{
+		// 方針が決まっている場合は対話できる入力でも読まない
+		{PolicyInclude, true, processor.LargeInclude},
+		{PolicySkip, true, processor.LargeSkip},
+		{PolicyTruncate, true, processor.LargeTruncate},
+		// ask（空を含む）は対話できなければデフォルトになる
+		{PolicyAsk, false, NonInteractiveDefault},
+		{"", false, NonInteractiveDefault},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			in := newFakeInput("y\n", tt.interactive)
+			c := NewConsole(in, io.Discard, LargeFileOptions{Policy: tt.policy})
+			if d := decide(t, c, "big.log"); d != tt.want {
+				t.Errorf("decision = %v, want %v", d, tt.want)
+			}
+			if in.read {
+				t.Error("input was read")
+			}
+		})
+	}
+}
+
+func TestConsoleAnswers(t *testing.T) {
+	tests := []struct {
+		name    string
+		answers string
+		files   []string
+		want    []processor.LargeFileDecision
+		output  string // 出力に含まれるべき文字列
+	}{
+		{name: "y", answers: "y\n", files: []string{"a.log"}, want: []processor.LargeFileDecision{processor.LargeInclude}},
+		{name: "yes", answers: "YES\n", files: []string{"a.log"}, want: []processor.LargeFileDecision{processor.LargeInclude}},
+		{name: "n", answers: "n\n", files: []string{"a.log"}, want: []processor.LargeFileDecision{processor.LargeSkip}},
+		{name: "default", answers: "\n", files: []string{"a.log"}, want: []processor.LargeFileDecision{processor.LargeSkip}},
+		{name: "t", answers: "t\n", files: []string{"a.log"}, want: []processor.LargeFileDecision{processor.LargeTruncate}},
+		{
+			// 以降のファイルは確認しない
+			name: "a", answers: "a\n", files: []string{"a.log", "b.csv"},
+			want: []processor.LargeFileDecision{processor.LargeInclude, processor.LargeInclude},
+		},
+		{
+			name: "n!", answers: "n!\n", files: []string{"a.log", "b.csv"},
+			want: []processor.LargeFileDecision{processor.LargeSkip, processor.LargeSkip},
+		},
+		{
+			// 同じ拡張子のファイルのみ記憶し、他の拡張子は再度確認する
+			name: "e", answers: "e\nt\ny\n", files: []string{"a.log", "b.LOG", "c.csv"},
+			want:   []processor.LargeFileDecision{processor.LargeTruncate, processor.LargeTruncate, processor.LargeInclude},
+			output: "Apply to every large *.log",
+		},
+		{
+			// 不明な回答は再度尋ねる
+			name: "e retry", answers: "e\nx\nn\n", files: []string{"a.log", "b.log"},
+			want: []processor.LargeFileDecision{processor.LargeSkip, processor.LargeSkip},
+		},
+		{
+			name: "p", answers: "p\ny\n", files: []string{"a.log"},
+			want: []processor.LargeFileDecision{processor.LargeInclude}, output: "first 20 lines of a.log",
+		},
+		{
+			name: "?", answers: "?\nn\n", files: []string{"a.log"},
+			want: []processor.LargeFileDecision{processor.LargeSkip}, output: "n! skip this and all remaining large files",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			c := NewConsole(newFakeInput(tt.answers, true), &out, LargeFileOptions{Policy: PolicyAsk})
+			for i, name := range tt.files {
+				if d := decide(t, c, name); d != tt.want[i] {
+					t.Errorf("decision for %s = %v, want %v", name, d, tt.want[i])
+				}
+			}
+			if !strings.Contains(out.String(), "Large file detected: a.log (2.0 MB)") {
+				t.Errorf("prompt not printed:\n%s", out.String())
+			}
+			if !strings.Contains(out.String(), tt.output) {
+				t.Errorf("output does not contain %q:\n%s", tt.output, out.String())
+			}
+		})
+	}
+}
+
+func TestConsoleInputClosed(t *testing.T) {
+	c := NewConsole(newFakeInput("", true), io.Discard, LargeFileOptions{})
+	d, err := c.DecideLarge(context.Background(), processor.LargeFile{Path: "a.log"})
+	if !errors.Is(err, io.EOF) || d != processor.LargeSkip {
+		t.Errorf("DecideLarge = (%v, %v), want (%v, EOF)", d, err, processor.LargeSkip)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"sync"
 )
 
 // InputPort は外部からの入力ソース（対話モードの標準入力など）を抽象化します。
@@ -12,6 +13,10 @@ type InputPort interface {
 
 	// Close は入力待ち（ブロッキング）を外部から強制的に解除するために使用します。
 	Close() error
+
+	// IsInteractive はユーザーが応答できる入力（端末）かどうかを返します。
+	// false の場合、Console はプロンプトを表示せず決定的なデフォルトを使用します。
+	IsInteractive() bool
 }
 
 // ErrInputClosed は入力ポートが閉じられたことを示すエラーです。
@@ -20,24 +25,20 @@ var ErrInputClosed = errors.New("input closed")
 // StandardInput は os.Stdin をラップし、InputPort インターフェースを実装します。
 // io.Pipe を使用することで、os.Stdin 自体を閉じることなく Read を中断可能にします。
 type StandardInput struct {
-	reader *io.PipeReader
-	writer *io.PipeWriter
+	reader      *io.PipeReader
+	writer      *io.PipeWriter
+	interactive bool
+	startOnce   sync.Once
 }
 
 // NewStandardInput は標準入力を使用する StandardInput を作成します。
 func NewStandardInput() *StandardInput {
 	pr, pw := io.Pipe()
-	s := &StandardInput{
-		reader: pr,
-		writer: pw,
+	return &StandardInput{
+		reader:      pr,
+		writer:      pw,
+		interactive: IsTerminal(os.Stdin),
 	}
-
-	// ゴルーチンで標準入力を監視し、パイプに流し込みます。
-	// アプリケーション終了時（os.Exit）までこのゴルーチンは待機し続ける可能性がありますが、
-	// CLIツールの特性上、リソースリークとしては許容範囲内と判断します。
-	go s.readStdin()
-
-	return s
 }
 
 // readStdin は標準入力からデータを読み込み、パイプに書き込みます。
@@ -59,13 +60,51 @@ func (s *StandardInput) readStdin() {
 }
 
 // Read はパイプからデータを読み込みます。
+// 初回の Read で標準入力の監視ゴルーチンを起動します。
+// 対話が不要な実行でパイプ経由のデータを消費しないよう、起動は必要になるまで遅延させます。
 func (s *StandardInput) Read(p []byte) (n int, err error) {
+	s.startOnce.Do(func() {
+		// ゴルーチンで標準入力を監視し、パイプに流し込みます。
+		// アプリケーション終了時（os.Exit）までこのゴルーチンは待機し続ける可能性がありますが、
+		// CLIツールの特性上、リソースリークとしては許容範囲内と判断します。
+		go s.readStdin()
+	})
 	return s.reader.Read(p)
 }
 
+// IsInteractive は標準入力が端末に接続されているかを返します。
+func (s *StandardInput) IsInteractive() bool {
+	return s.interactive
+}
+
 // Close はパイプの Writer 側をエラー付きで閉じます。
 // これにより、Read でブロックしているゴルーチンは即座にエラーを受け取って解除されます。
 // os.Stdin 自体は閉じません。
 func (s *StandardInput) Close() error {
 	return s.writer.CloseWithError(ErrInputClosed)
 }
+
+// NullInput は入力を持たない InputPort です。
+// 標準入力を対話以外の用途（アーカイブの読み込みなど）で使用する場合に、
+// os.Stdin を読み取るゴルーチンを起動しないために使用します。
+type NullInput struct{}
+
+// NewNullInput は常に EOF を返す InputPort を作成します。
+func NewNullInput() *NullInput {
+	return &NullInput{}
+}
+
+// Read は常に io.EOF を返します。
+func (NullInput) Read(p []byte) (int, error) {
+	return 0, io.EOF
+}
+
+// Close は何もしません。
+func (NullInput) Close() error {
+	return nil
+}
+
+// IsInteractive は常に false を返します。
+func (NullInput) IsInteractive() bool {
+	return false
+}
@@ -0,0 +1,185 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/kazuki-sk/codepack/internal/processor"
+)
+
+// progressInterval は進捗行の再描画間隔です。
+// ファイルごとに描画すると端末への出力がボトルネックになるため、一定間隔に間引きます。
+const progressInterval = 100 * time.Millisecond
+
+// maxProgressPath は進捗行に表示するパスの最大の文字数です（超過分は先頭を省略）。
+const maxProgressPath = 48
+
+// IsTerminal は f が端末（キャラクタデバイス）に接続されているかを返します。
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Progress は処理中の進捗を1行で表示します。
+// 他の出力（ログ・対話プロンプト）と混ざらないよう、それらの前に進捗行を消去します。
+type Progress struct {
+	out   io.Writer
+	start time.Time
+
+	mu      sync.Mutex
+	scanned int
+	packed  int
+	bytes   int64
+	current string
+	drawn   bool // 進捗行が画面上に表示されているか
+	paused  bool
+	dirty   bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewProgress は out へ描画する Progress を作成します。out は端末である前提です。
+func NewProgress(out io.Writer) *Progress {
+	return &Progress{out: out, done: make(chan struct{})}
+}
+
+// Start は一定間隔での再描画を開始します。
+func (p *Progress) Start() {
+	p.start = time.Now()
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				if p.dirty && !p.paused {
+					p.drawLocked()
+				}
+				p.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop は再描画を停止し、進捗行を消去します。
+func (p *Progress) Stop() {
+	close(p.done)
+	p.wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLocked()
+}
+
+// Visit は走査したファイルを記録します（processor.Options.OnVisit 用）。
+func (p *Progress) Visit(path string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scanned++
+	p.current = path
+	p.dirty = true
+}
+
+// Packed は出力したエントリを記録します（processor.Options.OnEntry 用）。
+func (p *Progress) Packed(e processor.Entry) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !e.Binary {
+		p.packed++
+	}
+	p.dirty = true
+}
+
+// Pause は進捗行を消去し、Resume まで再描画を止めます。
+// 対話プロンプトの表示・入力中に使用します。
+func (p *Progress) Pause() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLocked()
+	p.paused = true
+}
+
+// Resume は再描画を再開します。
+func (p *Progress) Resume() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.paused = false
+	p.dirty = true
+}
+
+// CountingWriter は w への書き込みバイト数を進捗に反映する Writer を返します。
+func (p *Progress) CountingWriter(w io.Writer) io.Writer {
+	return &progressCounter{p: p, w: w}
+}
+
+// LogWriter は進捗行を消去してから書き込む Writer を返します。
+// 進捗行と同じ端末へ出力するロガーに使用します。
+func (p *Progress) LogWriter() io.Writer {
+	return progressLogWriter{p: p}
+}
+
+// drawLocked は進捗行を描画します。呼び出し元で mu を保持している必要があります。
+func (p *Progress) drawLocked() {
+	path := shortenPath(p.current)
+	fmt.Fprintf(p.out, "\r\x1b[K[%d scanned | %d packed | %s | ~%d tokens | %s] %s",
+		p.scanned, p.packed, formatSize(p.bytes), processor.EstimateTokens(p.bytes),
+		time.Since(p.start).Round(100*time.Millisecond), path)
+	p.drawn = true
+	p.dirty = false
+}
+
+// shortenPath は path が maxProgressPath 文字を超える場合に、先頭を "..." に置き換えて短くします。
+// 文字（rune）単位で数えるため、日本語などのパスがマルチバイト文字の途中で切れることはありません。
+func shortenPath(path string) string {
+	runes := []rune(path)
+	if len(runes) <= maxProgressPath {
+		return path
+	}
+	return "..." + string(runes[len(runes)-maxProgressPath+3:])
+}
+
+// clearLocked は表示中の進捗行を消去します。呼び出し元で mu を保持している必要があります。
+func (p *Progress) clearLocked() {
+	if p.drawn {
+		fmt.Fprint(p.out, "\r\x1b[K")
+		p.drawn = false
+		p.dirty = true
+	}
+}
+
+type progressCounter struct {
+	p *Progress
+	w io.Writer
+}
+
+func (c *progressCounter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.p.mu.Lock()
+	c.p.bytes += int64(n)
+	c.p.dirty = true
+	c.p.mu.Unlock()
+	return n, err
+}
+
+type progressLogWriter struct {
+	p *Progress
+}
+
+func (l progressLogWriter) Write(b []byte) (int, error) {
+	l.p.mu.Lock()
+	defer l.p.mu.Unlock()
+	l.p.clearLocked()
+	return l.p.out.Write(b)
+}
@@ -0,0 +1,29 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestShortenPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"cmd/main.go", "cmd/main.go"},
+		{strings.Repeat("a", maxProgressPath), strings.Repeat("a", maxProgressPath)},
+		{"x/" + strings.Repeat("a", maxProgressPath), "..." + strings.Repeat("a", maxProgressPath-3)},
+		// マルチバイト文字は文字単位で数え、途中で切らない
+		{"ドキュメント/" + strings.Repeat("設計書", 20) + ".md", "..." + strings.Repeat("設計書", 14) + ".md"},
+	}
+	for _, tt := range tests {
+		got := shortenPath(tt.path)
+		if got != tt.want {
+			t.Errorf("shortenPath(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+		if !utf8.ValidString(got) || utf8.RuneCountInString(got) > maxProgressPath {
+			t.Errorf("shortenPath(%q) = %q is invalid or longer than %d characters", tt.path, got, maxProgressPath)
+		}
+	}
+}
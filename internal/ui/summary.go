@@ -0,0 +1,68 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/kazuki-sk/codepack/internal/processor"
+)
+
+// PrintSummary は実行結果の集計を人間向けの形式で出力します。
+// 読み込めなかったファイルは見落とされやすいため、パスと理由を個別に列挙します。
+func PrintSummary(w io.Writer, s processor.Stats) {
+	fmt.Fprintln(w, "\nSummary:")
+	fmt.Fprintf(w, "  Files packed:    %d\n", s.Files)
+	fmt.Fprintf(w, "  Binary skipped:  %d\n", s.Binary)
+	fmt.Fprintf(w, "  Ignored:         %d files, %d directories%s\n", s.IgnoredFiles, s.IgnoredDirs, ignoredBySource(s.Skipped))
+	fmt.Fprintf(w, "  Large declined:  %d\n", s.LargeSkipped)
+	fmt.Fprintf(w, "  Truncated:       %d\n", s.Truncated)
+	fmt.Fprintf(w, "  Unreadable:      %d\n", s.Unreadable)
+	if s.Generated > 0 {
+		fmt.Fprintf(w, "  Generated:       %d\n", s.Generated)
+	}
+	if s.Redacted > 0 {
+		fmt.Fprintf(w, "  Redacted:        %d secret(s)\n", s.Redacted)
+	}
+	fmt.Fprintf(w, "  Output:          %s (~%d tokens)\n", formatSize(s.Bytes), s.Tokens)
+	fmt.Fprintf(w, "  Elapsed:         %s\n", s.Elapsed.Round(1e6))
+
+	for _, sk := range s.Skipped {
+		if sk.Reason == processor.SkipUnreadable {
+			fmt.Fprintf(w, "  [unreadable] %s: %s\n", sk.Path, sk.Detail)
+		}
+	}
+	for _, r := range s.Redactions {
+		if r.Diff {
+			fmt.Fprintf(w, "  [redacted] %s (diff line %d): %s\n", r.Path, r.Line, r.Rule)
+		} else {
+			fmt.Fprintf(w, "  [redacted] %s:%d: %s\n", r.Path, r.Line, r.Rule)
+		}
+	}
+}
+
+// ignoredBySource は除外ルールの出所ごとの件数を " (default: 40, .gitignore: 3)" の形式で返します。
+func ignoredBySource(skips []processor.Skip) string {
+	counts := make(map[string]int)
+	for _, sk := range skips {
+		if sk.Reason == processor.SkipIgnored {
+			counts[sk.Detail]++
+		}
+	}
+	if len(counts) == 0 {
+		return ""
+	}
+
+	sources := make([]string, 0, len(counts))
+	for src := range counts {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+
+	parts := make([]string, len(sources))
+	for i, src := range sources {
+		parts[i] = fmt.Sprintf("%s: %d", src, counts[src])
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
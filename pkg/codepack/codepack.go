@@ -0,0 +1,439 @@
+package codepack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/kazuki-sk/codepack/internal/gitattr"
+	"github.com/kazuki-sk/codepack/internal/ignorer"
+	"github.com/kazuki-sk/codepack/internal/language"
+	"github.com/kazuki-sk/codepack/internal/processor"
+	"github.com/kazuki-sk/codepack/internal/secret"
+)
+
+// Matcher はパスが除外対象か判定するインターフェースです。
+// path は fs.FS のルートからの相対パス（スラッシュ区切り）です。
+type Matcher = ignorer.Matcher
+
+// LargeFileHandler は大容量ファイルを含めるかどうかを決定するインターフェースです。
+type LargeFileHandler = processor.LargeFileHandler
+
+// LargeFileDecider は切り詰めを含む判断を返せる LargeFileHandler の拡張です。
+type LargeFileDecider = processor.LargeFileDecider
+
+// LargeFile は LargeFileDecider に渡される判断材料です。Head で先頭行をプレビューできます。
+type LargeFile = processor.LargeFile
+
+// LargeFileDecision は大容量ファイルの扱いです。
+type LargeFileDecision = processor.LargeFileDecision
+
+// 大容量ファイルの扱い。
+const (
+	LargeSkip     = processor.LargeSkip
+	LargeInclude  = processor.LargeInclude
+	LargeTruncate = processor.LargeTruncate
+)
+
+// DefaultTruncateLines は切り詰め時に残す行数のデフォルト値です。
+const DefaultTruncateLines = processor.DefaultTruncateLines
+
+// DefaultNotebookOutputLines はノートブックのセルごとに残す出力の行数のデフォルト値です。
+const DefaultNotebookOutputLines = processor.DefaultNotebookOutputLines
+
+// Truncation は大容量ファイルの切り詰め方の設定です。
+type Truncation = processor.Truncation
+
+// TruncateMode は切り詰め方です。
+type TruncateMode = processor.TruncateMode
+
+// 切り詰め方。
+const (
+	TruncateHead     = processor.TruncateHead
+	TruncateHeadTail = processor.TruncateHeadTail
+	TruncateBytes    = processor.TruncateBytes
+)
+
+// SizeRule はパターンに一致するファイルの大容量判定の閾値です。
+type SizeRule = processor.SizeRule
+
+// Formatter は各エントリの書式（見出し・フェンス・差分ブロック）を定義するインターフェースです。
+type Formatter = processor.Formatter
+
+// LineNumberFormatter は行番号の書式を指定できる Formatter の拡張です（任意）。
+type LineNumberFormatter = processor.LineNumberFormatter
+
+// MarkdownFormatter は標準の Markdown 書式です。
+type MarkdownFormatter = processor.MarkdownFormatter
+
+// DiffProvider はファイルごとの差分を提供するインターフェースです。
+type DiffProvider = processor.DiffProvider
+
+// Entry は出力された1ファイル分の情報です。
+type Entry = processor.Entry
+
+// BinaryInfo はバイナリファイルの内容の代わりに出力されるメタデータ（形式・画像サイズ・アーカイブの内容・SHA-256）です。
+type BinaryInfo = processor.BinaryInfo
+
+// Snippet はファイルの一部（行の範囲）を出力する指定です。
+type Snippet = processor.Snippet
+
+// Replacement は内容（と見出しのパス）を書き換える正規表現の規則です。
+type Replacement = processor.Replacement
+
+// Stats は Pack の実行結果の集計です。
+type Stats = processor.Stats
+
+// DefaultThreshold は大容量ファイルとみなす閾値です。
+const DefaultThreshold = processor.DefaultThreshold
+
+// Options は Packer の設定です。ゼロ値は CLI のデフォルトと同じ挙動になります。
+type Options struct {
+	// DisableDefaultIgnores が true の場合、組み込みの除外ルール（.git/ や node_modules/ など）を使用しません。
+	DisableDefaultIgnores bool
+	// DisableGitAttributes が true の場合、fsys 内の .gitattributes を使用しません。
+	// 使用する場合、各ディレクトリの .gitattributes を git と同様に階層的に適用し、export-ignore のファイルを除外、
+	// linguist-language を言語の判定、binary・-diff / diff をバイナリの判定に使用します。
+	DisableGitAttributes bool
+	// IgnorePatterns は .gitignore 形式の追加除外パターンです。
+	IgnorePatterns []string
+	// Matchers は追加の除外判定です。組み込みルールと IgnorePatterns の後に評価されます。
+	Matchers []Matcher
+
+	// LanguageMap は拡張子と言語名の対応表（LinguistMap形式）です。組み込みの対応表に上書きマージされます。
+	LanguageMap map[string][]string
+
+	// TextPatterns / BinaryPatterns に一致するファイルは、内容によらずテキスト / バイナリとして扱います。
+	// path.Match 形式で、'/' を含む場合はルートからの相対パス、含まない場合はファイル名と照合します。
+	// 両方に一致する場合は TextPatterns が優先されます。
+	TextPatterns   []string
+	BinaryPatterns []string
+
+	// HexDumpBytes はバイナリファイルの先頭をダンプするバイト数です。0 の場合はダンプしません。
+	HexDumpBytes int
+
+	// StripComments が true の場合、言語ごとの構文（C 系・# 系・SQL・HTML/XML・Lisp）に従ってコメントを取り除きます。
+	// 文字列リテラル内のコメント記号は残ります。KeepDocComments が true の場合はドキュメントコメントを残します。
+	StripComments   bool
+	KeepDocComments bool
+	// CollapseBlankLines が true の場合、連続する空行を1行にまとめます。
+	CollapseBlankLines bool
+	// SkipGenerated が true の場合、生成されたファイルを出力しません。先頭の "Code generated ... DO NOT EDIT" や
+	// protoc・mockgen・sqlc・OpenAPI Generator の目印、.gitattributes の
+	// linguist-generated / linguist-vendored で判定します。除外したファイルは Stats.Skipped に記録されます。
+	SkipGenerated bool
+	// GeneratedPlaceholder が true の場合、SkipGenerated で除外するファイルを見出しと1行の説明のみのエントリとして出力します。
+	GeneratedPlaceholder bool
+
+	// RedactSecrets が true の場合、内容と差分に含まれる秘密情報（AWS キー・GitHub トークン・秘密鍵のブロック・JWT、
+	// password や token という名前に代入されたランダムな文字列）を [REDACTED:rule-id] に置き換えます。
+	// 置き換えた位置は Stats.Redactions に記録されます（値そのものは記録しません）。
+	RedactSecrets bool
+	// SecretAllowlist は置き換えない値の正規表現です（テスト用のダミー値など）。
+	SecretAllowlist []string
+	// FailOnSecret が true の場合、秘密情報を置き換えれば走査完了後に ErrSecretsFound を返します（RedactSecrets を含みます）。
+	FailOnSecret bool
+	// Replacements は内容を1行ずつ書き換える規則です（社内のホスト名・顧客名・メールアドレスの除去など）。
+	// 秘密情報の置き換えの後に指定順に適用され、差分にも適用されます。
+	Replacements []Replacement
+
+	// LineNumbers が true の場合、内容の各行の先頭に元のファイルでの行番号（"  12 | "）を付けます。
+	// 番号は StripComments などで削除した行も数えます。ノートブックの変換やアウトラインには付けません。
+	// Formatter が LineNumberFormatter を実装している場合は、その書式を使用します。
+	LineNumbers bool
+
+	// Outline が true の場合、Go のソースを package 句・import・型宣言・関数のシグネチャ（ドキュメントコメント付き）に要約します。
+	// 関数本体は { ... } に置き換えられます。解析できないファイルは全体を出力します。
+	// TypeScript/JavaScript・Java・Rust・C/C++/C# は括弧の対応から、Python はインデントから関数本体を推定して省略します
+	// （クラス・構造体などの本体はメンバーのシグネチャとして残ります）。
+	Outline bool
+
+	// RawNotebooks が true の場合、Jupyter ノートブック (.ipynb) をセルの並びに変換せず JSON のまま出力します。
+	RawNotebooks bool
+	// NotebookOutputLines はノートブックのセルごとに残す出力の行数です。
+	// 0 の場合は DefaultNotebookOutputLines、負の場合は出力を含めません。
+	NotebookOutputLines int
+
+	// MaxFileSize は大容量ファイルとみなすサイズです。0 の場合は DefaultThreshold です。
+	MaxFileSize int64
+	// SizeOverrides はパターンごとの閾値です。複数一致した場合は後のものが優先されます。
+	SizeOverrides []SizeRule
+
+	// LargeFileHandler は閾値を超えるファイルの扱いを決定します。
+	// LargeFileDecider も実装している場合は、切り詰めを含む判断が使用されます。
+	// nil の場合、大容量ファイルは除外されます。
+	LargeFileHandler LargeFileHandler
+	// Truncation は大容量ファイルを切り詰める場合の設定です。ゼロ値は先頭 DefaultTruncateLines 行です。
+	Truncation Truncation
+	// Formatter はエントリの書式です。nil の場合は MarkdownFormatter を使用します。
+	Formatter Formatter
+
+	// Preamble は最初のファイルの前に書き込むテキスト（Markdown）です。空の場合は書き込みません。
+	Preamble string
+	// Snippets は走査したファイルの後に出力する、ファイルの一部（行の範囲）です。
+	// 見出しには範囲が示されます。除外ルールは適用されません。
+	Snippets []Snippet
+	// SnippetsOnly が true の場合、fsys を走査せず Snippets のみを出力します。
+	SnippetsOnly bool
+
+	// Diff は差分の提供元です。nil の場合は差分を出力しません。
+	Diff DiffProvider
+	// CombinedDiff が true の場合、差分を各ファイルの直後ではなく末尾にまとめて出力します。
+	CombinedDiff bool
+
+	// Dir は fsys に対応するディスク上のディレクトリです。
+	// OutputFile と合わせて、出力ファイル自身を読み込まないための判定に使用します。
+	Dir string
+	// OutputFile は出力先のファイルパスです（ファイルに出力しない場合は空）。
+	OutputFile string
+
+	// OnVisit は除外判定の前に、走査した各ファイルのパスで呼ばれるフックです。
+	OnVisit func(path string)
+	// OnEntry は各エントリの書き込み完了後に呼ばれるフックです。
+	OnEntry func(Entry)
+
+	// Logger はスキップ理由（reason 属性）などの記録先です。nil の場合は出力しません。
+	Logger *slog.Logger
+	// Strict が true の場合、読み込めないファイルがあれば走査完了後に ErrUnreadable を返します。
+	Strict bool
+}
+
+// ErrUnreadable は Options.Strict 指定時に、読み込めないファイルがあったことを示します。
+var ErrUnreadable = processor.ErrUnreadable
+
+// ErrSecretsFound は Options.FailOnSecret 指定時に、秘密情報を置き換えたことを示します。
+var ErrSecretsFound = processor.ErrSecretsFound
+
+// Redaction は秘密情報を置き換えた1か所の記録です。
+type Redaction = processor.Redaction
+
+// Packer は Options に基づいて fs.FS をまとめます。
+// 1つの Packer を複数回の Pack に再利用できますが、並行呼び出しには対応しません。
+type Packer struct {
+	opts    Options
+	mapper  *language.Mapper
+	secrets *secret.Scanner
+}
+
+// New は Options を検証し、Packer を作成します。
+func New(opts Options) (*Packer, error) {
+	mapper, err := language.NewMapper("")
+	if err != nil {
+		return nil, err
+	}
+	mapper.Merge(opts.LanguageMap)
+
+	var secrets *secret.Scanner
+	if opts.RedactSecrets || opts.FailOnSecret {
+		if secrets, err = secret.NewScanner(opts.SecretAllowlist); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Packer{opts: opts, mapper: mapper, secrets: secrets}, nil
+}
+
+// Pack は fsys を走査し、w へ書き込みます。
+// w のフラッシュやクローズは呼び出し元の責務です。
+// ctx がキャンセルされた場合は context.Canceled を返します。
+func (p *Packer) Pack(ctx context.Context, fsys fs.FS, w io.Writer) (Stats, error) {
+	ignr := ignorer.NewIgnorer()
+	ignr.SetLogger(p.opts.Logger)
+	if !p.opts.DisableDefaultIgnores {
+		if err := ignr.LoadDefaults(); err != nil {
+			return Stats{}, err
+		}
+	}
+	if len(p.opts.IgnorePatterns) > 0 {
+		patterns := strings.NewReader(strings.Join(p.opts.IgnorePatterns, "\n"))
+		ignr.AddMatcher(NamedMatcher("pattern", NewGitIgnoreMatcher(patterns)))
+	}
+	for _, m := range p.opts.Matchers {
+		ignr.AddMatcher(m)
+	}
+
+	// .gitattributes は除外済みのディレクトリ（.git/ など）を除いて読み込み、export-ignore を除外ルールに加える
+	var attrs *gitattr.Attributes
+	mapper := p.mapper
+	if !p.opts.DisableGitAttributes {
+		var err error
+		attrs, err = gitattr.LoadAll(fsys, func(dir string) bool { return ignr.ShouldIgnore(dir, true) })
+		if err != nil {
+			return Stats{}, err
+		}
+		if attrs != nil {
+			ignr.AddMatcher(NamedMatcher(gitattr.FileName, attrs))
+			mapper = mapper.WithOverride(attrs.Language)
+		}
+	}
+
+	lfh := p.opts.LargeFileHandler
+	if lfh == nil {
+		lfh = LargeFileHandlerFunc(func(context.Context, string, int64) (bool, error) {
+			return false, nil
+		})
+	}
+
+	diffStyle := processor.DiffInline
+	if p.opts.CombinedDiff {
+		diffStyle = processor.DiffCombined
+	}
+
+	proc, err := processor.NewProcessor(fsys, w, processor.Options{
+		TargetDir:            p.opts.Dir,
+		OutputFile:           p.opts.OutputFile,
+		Ignorer:              ignr,
+		Mapper:               mapper,
+		LargeFileHandler:     lfh,
+		TextPatterns:         p.opts.TextPatterns,
+		BinaryPatterns:       p.opts.BinaryPatterns,
+		HexDumpBytes:         p.opts.HexDumpBytes,
+		Attributes:           attrs,
+		SkipGenerated:        p.opts.SkipGenerated,
+		GeneratedPlaceholder: p.opts.GeneratedPlaceholder,
+		StripComments:        p.opts.StripComments,
+		KeepDocComments:      p.opts.KeepDocComments,
+		CollapseBlankLines:   p.opts.CollapseBlankLines,
+		Secrets:              p.secrets,
+		FailOnSecret:         p.opts.FailOnSecret,
+		Replacements:         p.opts.Replacements,
+		LineNumbers:          p.opts.LineNumbers,
+		Outline:              p.opts.Outline,
+		RawNotebooks:         p.opts.RawNotebooks,
+		NotebookOutputLines:  p.opts.NotebookOutputLines,
+		Threshold:            p.opts.MaxFileSize,
+		SizeOverrides:        p.opts.SizeOverrides,
+		Truncation:           p.opts.Truncation,
+		Formatter:            p.opts.Formatter,
+		Preamble:             p.opts.Preamble,
+		Snippets:             p.opts.Snippets,
+		SnippetsOnly:         p.opts.SnippetsOnly,
+		Diffs:                p.opts.Diff,
+		DiffStyle:            diffStyle,
+		OnVisit:              p.opts.OnVisit,
+		OnEntry:              p.opts.OnEntry,
+		Logger:               p.opts.Logger,
+		Strict:               p.opts.Strict,
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return proc.Execute(ctx)
+}
+
+// LargeFileHandlerFunc は関数を LargeFileHandler として扱うためのアダプタです。
+type LargeFileHandlerFunc func(ctx context.Context, path string, size int64) (bool, error)
+
+// ShouldInclude は f(ctx, path, size) を呼び出します。
+func (f LargeFileHandlerFunc) ShouldInclude(ctx context.Context, path string, size int64) (bool, error) {
+	return f(ctx, path, size)
+}
+
+// NewGitIgnoreMatcher は .gitignore 形式のルールを r から読み込んだ Matcher を作成します。
+func NewGitIgnoreMatcher(r io.Reader) Matcher {
+	return ignorer.NewGitIgnoreMatcher(r)
+}
+
+// NamedMatcher は m に出所 source を付与します。
+// 出所は Stats.Skipped の Detail に、除外の根拠として記録されます。
+func NamedMatcher(source string, m Matcher) Matcher {
+	return ignorer.Named(source, m)
+}
+
+// LoadIgnoreFile は fsys 上の .gitignore 形式のファイルを読み込みます。
+// 返される Matcher の出所は name です。ファイルが存在しない場合は (nil, nil) を返します。
+func LoadIgnoreFile(fsys fs.FS, name string) (Matcher, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	return NamedMatcher(name, NewGitIgnoreMatcher(f)), nil
+}
+
+// LoadLanguageMap は LinguistMap 形式の JSON ファイルを読み込みます。
+func LoadLanguageMap(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string][]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ParseSnippet は "path:120-180"（行の範囲）または "path#run"（宣言の名前）の形式の指定を解析します。
+func ParseSnippet(spec string) (Snippet, error) {
+	return processor.ParseSnippet(spec)
+}
+
+// LoadSnippetFile は1行に1つの指定を並べたファイルを読み込みます。空行と # で始まる行は無視します。
+func LoadSnippetFile(path string) ([]Snippet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return processor.ReadSnippets(f)
+}
+
+// LoadSecretAllowlist は1行に1つの正規表現を並べた許可リストのファイルを読み込みます。空行と # で始まる行は無視します。
+func LoadSecretAllowlist(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return secret.ReadAllowlist(f)
+}
+
+// LoadReplaceRules は書き換えの規則を JSON ファイルから読み込みます。
+// 形式は {"pattern": 正規表現, "replacement": 置き換え後, "glob": 対象ファイル, "paths": 見出しのパスにも適用するか} の配列です。
+func LoadReplaceRules(name string) ([]Replacement, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var rules []struct {
+		Pattern     string `json:"pattern"`
+		Replacement string `json:"replacement"`
+		Glob        string `json:"glob"`
+		Paths       bool   `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	replacements := make([]Replacement, 0, len(rules))
+	for i, r := range rules {
+		if r.Pattern == "" {
+			return nil, fmt.Errorf("rule %d: pattern is required", i+1)
+		}
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		if _, err := path.Match(r.Glob, ""); err != nil {
+			return nil, fmt.Errorf("rule %d: invalid glob %q: %w", i+1, r.Glob, err)
+		}
+		replacements = append(replacements, Replacement{Pattern: re, Replacement: r.Replacement, Glob: r.Glob, Paths: r.Paths})
+	}
+	return replacements, nil
+}
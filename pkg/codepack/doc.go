@@ -0,0 +1,27 @@
+// Package codepack は、ソースツリーを LLM へのコンテキスト提供に適した
+// 単一のドキュメントへまとめるためのライブラリ API です。
+// codepack CLI (cmd/codepack) もこのパッケージの利用者の1つです。
+//
+// 基本的な使い方:
+//
+//	packer, err := codepack.New(codepack.Options{
+//		IgnorePatterns: []string{"*.log"},
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	stats, err := packer.Pack(ctx, os.DirFS("."), w)
+//
+// fsys には os.DirFS のほか、zip.Reader や testing/fstest.MapFS など任意の fs.FS を渡せます。
+//
+// # 互換性
+//
+// 本パッケージの公開 API（型・関数・Options のフィールド）は Go モジュールの
+// セマンティックバージョニングに従います。同一メジャーバージョン内では、
+//   - 既存の識別子の削除やシグネチャの変更は行いません。
+//   - Options へのフィールド追加は行うことがあります。ゼロ値は常に従来と同じ挙動になります。
+//   - インターフェース（Matcher, LargeFileHandler, Formatter, DiffProvider）へのメソッド追加は行いません。
+//
+// 出力フォーマットの細部（空行の位置など）は互換性保証の対象外です。
+// 安定した書式が必要な場合は独自の Formatter を実装してください。
+package codepack
@@ -0,0 +1,76 @@
+package codepack_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing/fstest"
+
+	"github.com/kazuki-sk/codepack/pkg/codepack"
+)
+
+func ExampleNew() {
+	packer, err := codepack.New(codepack.Options{
+		IgnorePatterns: []string{"*.log"},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fsys := fstest.MapFS{
+		"main.go":   {Data: []byte("package main\n")},
+		"debug.log": {Data: []byte("noise\n")},
+	}
+	if _, err := packer.Pack(context.Background(), fsys, os.Stdout); err != nil {
+		fmt.Println(err)
+	}
+	// Output:
+	// ## File: main.go
+	//
+	// ```Go
+	// package main
+	//
+	// ```
+}
+
+func ExamplePacker_Pack() {
+	packer, err := codepack.New(codepack.Options{
+		// 除外の判定は Matcher で差し替えられる
+		Matchers: []codepack.Matcher{
+			codepack.NamedMatcher("no-tests", codepack.NewGitIgnoreMatcher(strings.NewReader("*_test.go"))),
+		},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fsys := fstest.MapFS{
+		"app.go":          {Data: []byte("package app\n")},
+		"app_test.go":     {Data: []byte("package app\n")},
+		"assets/logo.png": {Data: []byte("\x89PNG\r\n\x1a\n\x00\x00")},
+	}
+	var out strings.Builder
+	stats, err := packer.Pack(context.Background(), fsys, &out)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Printf("files=%d binary=%d ignored=%d\n", stats.Files, stats.Binary, stats.IgnoredFiles)
+	for _, s := range stats.Skipped {
+		fmt.Printf("skipped %s (%s: %s)\n", s.Path, s.Reason, s.Detail)
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		if strings.HasPrefix(line, "## File:") {
+			fmt.Println(line)
+		}
+	}
+	// Output:
+	// files=1 binary=1 ignored=1
+	// skipped app_test.go (ignored: no-tests)
+	// ## File: app.go
+	// ## File: assets/logo.png
+}